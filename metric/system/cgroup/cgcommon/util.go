@@ -81,3 +81,30 @@ func ParseCgroupParamKeyValue(t string) (string, uint64, error) {
 
 	return parts[0], value, nil
 }
+
+// CountListEntries returns the number of entries in a kernel list format string, such as 0-3,8,10-11
+func CountListEntries(list string) (int, error) {
+	count := 0
+	for _, part := range strings.Split(list, ",") {
+		if part == "" {
+			continue
+		}
+		low, high, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(low)
+		if err != nil {
+			return 0, err
+		}
+		end := start
+		if isRange {
+			end, err = strconv.Atoi(high)
+			if err != nil {
+				return 0, err
+			}
+		}
+		if end < start {
+			return 0, fmt.Errorf("invalid range '%s'", part)
+		}
+		count += end - start + 1
+	}
+	return count, nil
+}
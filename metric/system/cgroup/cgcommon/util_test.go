@@ -0,0 +1,43 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cgcommon
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountListEntries(t *testing.T) {
+	for list, want := range map[string]int{
+		"0":           1,
+		"0-7":         8,
+		"0-3,8,10-11": 7,
+		"":            0,
+	} {
+		got, err := CountListEntries(list)
+		require.NoError(t, err, list)
+		assert.Equal(t, want, got, list)
+	}
+
+	_, err := CountListEntries("3-1")
+	assert.Error(t, err)
+	_, err = CountListEntries("a-b")
+	assert.Error(t, err)
+}
@@ -26,11 +26,10 @@ import (
 	"time"
 
 	"github.com/elastic/elastic-agent-system-metrics/metric/system/cgroup/cgcommon"
-	"github.com/elastic/gosigar/sys/linux"
+	"github.com/elastic/elastic-agent-system-metrics/metric/system/resolve"
+	"github.com/elastic/elastic-agent-system-metrics/metric/system/sysconf"
 )
 
-var clockTicks = uint64(linux.GetClockTicks())
-
 // CPUAccountingSubsystem contains metrics from the "cpuacct" subsystem.
 // Note that percentage values are not taken from cgroup metrics, but derived via FillPercentages()
 type CPUAccountingSubsystem struct {
@@ -51,8 +50,14 @@ type CPUAccountingStats struct {
 // Get reads metrics from the "cpuacct" subsystem. path is the filepath to the
 // cgroup hierarchy to read.
 func (cpuacct *CPUAccountingSubsystem) Get(path string) error {
+	return cpuacct.GetWithHostfs(path, resolve.NewTestResolver("/"))
+}
+
+// GetWithHostfs reads metrics from the "cpuacct" subsystem, like Get.
+// The clock tick rate used to convert CPU times is detected from the procfs of hostfs.
+func (cpuacct *CPUAccountingSubsystem) GetWithHostfs(path string, hostfs resolve.Resolver) error {
 	cpuacct.UsagePerCPU = make(map[string]uint64)
-	if err := cpuacctStat(path, cpuacct); err != nil {
+	if err := cpuacctStat(path, sysconf.ClockTicks(hostfs), cpuacct); err != nil {
 		return fmt.Errorf("error fetching cpuacct stats: %w", err)
 	}
 
@@ -67,7 +72,7 @@ func (cpuacct *CPUAccountingSubsystem) Get(path string) error {
 	return nil
 }
 
-func cpuacctStat(path string, cpuacct *CPUAccountingSubsystem) error {
+func cpuacctStat(path string, clockTicks uint64, cpuacct *CPUAccountingSubsystem) error {
 	f, err := os.Open(filepath.Join(path, "cpuacct.stat"))
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -85,9 +90,9 @@ func cpuacctStat(path string, cpuacct *CPUAccountingSubsystem) error {
 		}
 		switch t {
 		case "user":
-			cpuacct.Stats.User.NS = convertJiffiesToNanos(v)
+			cpuacct.Stats.User.NS = convertJiffiesToNanos(v, clockTicks)
 		case "system":
-			cpuacct.Stats.System.NS = convertJiffiesToNanos(v)
+			cpuacct.Stats.System.NS = convertJiffiesToNanos(v, clockTicks)
 		}
 	}
 
@@ -130,6 +135,7 @@ func cpuacctUsagePerCPU(path string, cpuacct *CPUAccountingSubsystem) error {
 	return nil
 }
 
-func convertJiffiesToNanos(j uint64) uint64 {
+// convertJiffiesToNanos converts a value in clock ticks (USER_HZ) into nanoseconds.
+func convertJiffiesToNanos(j uint64, clockTicks uint64) uint64 {
 	return (j * uint64(time.Second)) / clockTicks
 }
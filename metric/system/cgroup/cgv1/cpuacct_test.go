@@ -28,7 +28,7 @@ const cpuacctPath = "../testdata/docker/sys/fs/cgroup/cpuacct/docker/b29faf21b7e
 
 func TestCPUAccountingStats(t *testing.T) {
 	cpuacct := CPUAccountingSubsystem{}
-	if err := cpuacctStat(cpuacctPath, &cpuacct); err != nil {
+	if err := cpuacctStat(cpuacctPath, 100, &cpuacct); err != nil {
 		t.Fatal(err)
 	}
 
@@ -22,6 +22,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/elastic/elastic-agent-libs/opt"
 	"github.com/elastic/elastic-agent-system-metrics/metric/system/cgroup/cgcommon"
@@ -36,6 +38,21 @@ type CPUSubsystem struct {
 	Pressure map[string]cgcommon.Pressure `json:"pressure,omitempty" struct:"pressure,omitempty"`
 	// Stats shows overall counters for the CPU controller
 	Stats CPUStats
+	// Max is the CPU bandwidth limit from cpu.max
+	Max CPUMax `json:"max,omitempty" struct:"max,omitempty"`
+}
+
+// CPUMax carries the information from the cpu.max cgroup file
+type CPUMax struct {
+	// Quota is the CPU time in microseconds the cgroup may use in each period, unset if there is no limit.
+	Quota opt.Uint `json:"quota,omitempty" struct:"quota,omitempty"`
+	// Period is the length of a period in microseconds
+	Period opt.Uint `json:"period,omitempty" struct:"period,omitempty"`
+}
+
+// IsZero implements the IsZero interface for CPUMax
+func (t CPUMax) IsZero() bool {
+	return t.Quota.IsZero() && t.Period.IsZero()
 }
 
 // CPUStats carries the information from the cpu.stat cgroup file
@@ -63,6 +80,11 @@ func (t ThrottledField) IsZero() bool {
 func (cpu *CPUSubsystem) Get(path string) error {
 
 	var err error
+	cpu.Max, err = getMax(path)
+	if err != nil {
+		return fmt.Errorf("error fetching CPU max data: %w", err)
+	}
+
 	cpu.Pressure, err = cgcommon.GetPressure(filepath.Join(path, "cpu.pressure"))
 	// Not all systems have pressure stats. Treat this as a soft error.
 	if os.IsNotExist(err) {
@@ -80,6 +102,38 @@ func (cpu *CPUSubsystem) Get(path string) error {
 	return nil
 }
 
+// getMax returns the cpu.max data, which has the form "$MAX $PERIOD", where $MAX can be "max" for no limit.
+// The root cgroup has no cpu.max file.
+func getMax(path string) (CPUMax, error) {
+	raw, err := os.ReadFile(filepath.Join(path, "cpu.max"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return CPUMax{}, nil
+		}
+		return CPUMax{}, fmt.Errorf("error reading cpu.max: %w", err)
+	}
+
+	fields := strings.Fields(string(raw))
+	if len(fields) != 2 {
+		return CPUMax{}, fmt.Errorf("unexpected format of cpu.max: '%s'", raw)
+	}
+	data := CPUMax{}
+	if fields[0] != "max" {
+		quota, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			return data, fmt.Errorf("error parsing cpu.max quota: %w", err)
+		}
+		data.Quota = opt.UintWith(quota)
+	}
+	period, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return data, fmt.Errorf("error parsing cpu.max period: %w", err)
+	}
+	data.Period = opt.UintWith(period)
+
+	return data, nil
+}
+
 // getStats returns the cpu.stats data
 func getStats(path string) (CPUStats, error) {
 	f, err := os.Open(filepath.Join(path, "cpu.stat"))
@@ -18,9 +18,13 @@
 package cgv2
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/elastic-agent-libs/opt"
 )
 
 const v2Path = "../testdata/docker/sys/fs/cgroup/system.slice/docker-1c8fa019edd4b9d4b2856f4932c55929c5c118c808ed5faee9a135ca6e84b039.scope"
@@ -188,3 +192,21 @@ func TestGetCPU(t *testing.T) {
 	assert.Equal(t, uint64(26772130245), cpu.Stats.Usage.NS)
 	assert.Equal(t, uint64(5793060316), cpu.Stats.System.NS)
 }
+
+func TestGetMax(t *testing.T) {
+	// no limit
+	got, err := getMax("../testdata/docker/sys/fs/cgroup/system.slice")
+	assert.NoError(t, err)
+	assert.Equal(t, CPUMax{Period: opt.UintWith(100000)}, got)
+
+	// the root cgroup has no cpu.max
+	got, err = getMax(v2Path + "/missing")
+	assert.NoError(t, err)
+	assert.True(t, got.IsZero())
+
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "cpu.max"), []byte("150000 100000\n"), 0o644))
+	got, err = getMax(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, CPUMax{Quota: opt.UintWith(150000), Period: opt.UintWith(100000)}, got)
+}
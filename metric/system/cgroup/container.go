@@ -0,0 +1,144 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cgroup
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ContainerRuntime is the container runtime that created a cgroup
+type ContainerRuntime string
+
+const (
+	// RuntimeDocker is the Docker engine
+	RuntimeDocker ContainerRuntime = "docker"
+	// RuntimeContainerd is containerd, usually through its CRI plugin
+	RuntimeContainerd ContainerRuntime = "containerd"
+	// RuntimeCRIO is the CRI-O runtime
+	RuntimeCRIO ContainerRuntime = "cri-o"
+	// RuntimePodman is Podman, which uses libpod
+	RuntimePodman ContainerRuntime = "podman"
+	// RuntimeLXC is LXC or LXD
+	RuntimeLXC ContainerRuntime = "lxc"
+)
+
+// Kubernetes QoS classes
+const (
+	QOSGuaranteed = "guaranteed"
+	QOSBurstable  = "burstable"
+	QOSBestEffort = "besteffort"
+)
+
+// ContainerInfo is the container and Kubernetes pod metadata that can be derived from a cgroup path.
+type ContainerInfo struct {
+	// Runtime is empty if the path identifies a container, but not its runtime,
+	// such as a container in a Kubernetes pod with the cgroupfs driver.
+	Runtime ContainerRuntime `json:"runtime,omitempty" struct:"runtime,omitempty"`
+	// ID is the container ID, or the container name for LXC.
+	ID       string `json:"id,omitempty" struct:"id,omitempty"`
+	PodUID   string `json:"pod_uid,omitempty" struct:"pod_uid,omitempty"`
+	QOSClass string `json:"qos_class,omitempty" struct:"qos_class,omitempty"`
+}
+
+// IsZero returns true if no container or pod was found
+func (c ContainerInfo) IsZero() bool {
+	return c == ContainerInfo{}
+}
+
+var (
+	// A container scope with the systemd driver, or a container cgroup with the cgroupfs driver.
+	// The conmon monitor processes of CRI-O and Podman (crio-conmon-, libpod-conmon-) are not part of a container.
+	containerIDRegexp = regexp.MustCompile(`^(?:(docker|cri-containerd|crio|libpod)-)?([0-9a-f]{64})(?:\.scope)?$`)
+	// A pod cgroup, either pod<uid> with the cgroupfs driver, or kubepods-<qos>-pod<uid>.slice with the systemd
+	// driver, where the dashes in the UID are replaced by underscores.
+	podUIDRegexp = regexp.MustCompile(`pod([0-9a-f]{8}[-_][0-9a-f]{4}[-_][0-9a-f]{4}[-_][0-9a-f]{4}[-_][0-9a-f]{12})(?:\.slice)?$`)
+)
+
+var containerRuntimePrefixes = map[string]ContainerRuntime{
+	"docker":         RuntimeDocker,
+	"cri-containerd": RuntimeContainerd,
+	"crio":           RuntimeCRIO,
+	"libpod":         RuntimePodman,
+}
+
+// ParseContainerInfo extracts the container runtime, container ID, Kubernetes pod UID and QoS class
+// from a cgroup path, such as /kubepods/burstable/pod<uid>/<id> or /system.slice/docker-<id>.scope.
+// Both the cgroupfs and systemd driver layouts are supported, for cgroups V1 and V2.
+// The second return value is false if the path does not belong to a container or pod.
+func ParseContainerInfo(path string) (ContainerInfo, bool) {
+	info := ContainerInfo{}
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+
+	for i, segment := range segments {
+		if match := podUIDRegexp.FindStringSubmatch(segment); match != nil {
+			info.PodUID = strings.ReplaceAll(match[1], "_", "-")
+			info.QOSClass = QOSGuaranteed
+			for _, parent := range segments[:i] {
+				switch {
+				case parent == QOSBurstable, parent == "kubepods-burstable.slice":
+					info.QOSClass = QOSBurstable
+				case parent == QOSBestEffort, parent == "kubepods-besteffort.slice":
+					info.QOSClass = QOSBestEffort
+				}
+			}
+			continue
+		}
+
+		if match := containerIDRegexp.FindStringSubmatch(segment); match != nil {
+			info.ID = match[2]
+			info.Runtime = containerRuntimePrefixes[match[1]]
+			// with the cgroupfs driver, docker and podman put containers under a parent cgroup
+			if info.Runtime == "" && i > 0 {
+				switch segments[i-1] {
+				case "docker":
+					info.Runtime = RuntimeDocker
+				case "libpod_parent":
+					info.Runtime = RuntimePodman
+				}
+			}
+			continue
+		}
+
+		// LXC uses the container name, as /lxc/<name> or /lxc.payload.<name> or /lxc.payload/<name>
+		if name := strings.TrimPrefix(segment, "lxc.payload."); name != segment {
+			info.Runtime, info.ID = RuntimeLXC, name
+		} else if i > 0 && (segments[i-1] == "lxc" || segments[i-1] == "lxc.payload") {
+			info.Runtime, info.ID = RuntimeLXC, segment
+		}
+	}
+
+	return info, !info.IsZero()
+}
+
+// getContainerInfo returns the container info from the first of the given controller paths that belongs to a container.
+// On cgroup v1 the controllers can have different paths, so they are checked in a fixed order.
+func getContainerInfo(paths map[string]ControllerPath) ContainerInfo {
+	controllers := make([]string, 0, len(paths))
+	for controller := range paths {
+		controllers = append(controllers, controller)
+	}
+	sort.Strings(controllers)
+	for _, controller := range controllers {
+		if info, ok := ParseContainerInfo(paths[controller].ControllerPath); ok {
+			return info
+		}
+	}
+	return ContainerInfo{}
+}
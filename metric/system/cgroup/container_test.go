@@ -0,0 +1,139 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cgroup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseContainerInfo(t *testing.T) {
+	const (
+		cid    = "b29faf21b7eff959f64b4192c34d5d67a707fe8561e9eaa608cb27693fba4242"
+		podUID = "7e6a5e92-f1d3-4c3b-9f5e-2a3c4b5d6e7f"
+		podSD  = "7e6a5e92_f1d3_4c3b_9f5e_2a3c4b5d6e7f"
+	)
+
+	tests := []struct {
+		name string
+		path string
+		want ContainerInfo
+	}{
+		{
+			name: "docker cgroupfs",
+			path: "/docker/" + cid,
+			want: ContainerInfo{Runtime: RuntimeDocker, ID: cid},
+		},
+		{
+			name: "docker systemd",
+			path: "/system.slice/docker-" + cid + ".scope",
+			want: ContainerInfo{Runtime: RuntimeDocker, ID: cid},
+		},
+		{
+			name: "kubernetes cgroupfs burstable",
+			path: "/kubepods/burstable/pod" + podUID + "/" + cid,
+			want: ContainerInfo{ID: cid, PodUID: podUID, QOSClass: QOSBurstable},
+		},
+		{
+			name: "kubernetes cgroupfs guaranteed cri-o",
+			path: "/kubepods/pod" + podUID + "/crio-" + cid,
+			want: ContainerInfo{Runtime: RuntimeCRIO, ID: cid, PodUID: podUID, QOSClass: QOSGuaranteed},
+		},
+		{
+			name: "kubernetes systemd containerd",
+			path: "/kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-pod" + podSD + ".slice/cri-containerd-" + cid + ".scope",
+			want: ContainerInfo{Runtime: RuntimeContainerd, ID: cid, PodUID: podUID, QOSClass: QOSBestEffort},
+		},
+		{
+			name: "kubernetes systemd guaranteed cri-o",
+			path: "/kubepods.slice/kubepods-pod" + podSD + ".slice/crio-" + cid + ".scope",
+			want: ContainerInfo{Runtime: RuntimeCRIO, ID: cid, PodUID: podUID, QOSClass: QOSGuaranteed},
+		},
+		{
+			name: "kubernetes pod sandbox level",
+			path: "/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod" + podSD + ".slice",
+			want: ContainerInfo{PodUID: podUID, QOSClass: QOSBurstable},
+		},
+		{
+			name: "cri-o conmon",
+			path: "/kubepods.slice/kubepods-pod" + podSD + ".slice/crio-conmon-" + cid + ".scope",
+			want: ContainerInfo{PodUID: podUID, QOSClass: QOSGuaranteed},
+		},
+		{
+			name: "podman rootful",
+			path: "/machine.slice/libpod-" + cid + ".scope/container",
+			want: ContainerInfo{Runtime: RuntimePodman, ID: cid},
+		},
+		{
+			name: "podman rootless",
+			path: "/user.slice/user-1000.slice/user@1000.service/user.slice/libpod-" + cid + ".scope",
+			want: ContainerInfo{Runtime: RuntimePodman, ID: cid},
+		},
+		{
+			name: "podman cgroupfs",
+			path: "/libpod_parent/" + cid,
+			want: ContainerInfo{Runtime: RuntimePodman, ID: cid},
+		},
+		{
+			name: "lxc v1",
+			path: "/lxc/web01",
+			want: ContainerInfo{Runtime: RuntimeLXC, ID: "web01"},
+		},
+		{
+			name: "lxc v2",
+			path: "/lxc.payload.web01/system.slice/cron.service",
+			want: ContainerInfo{Runtime: RuntimeLXC, ID: "web01"},
+		},
+		{
+			name: "docker in lxc",
+			path: "/lxc.payload.web01/system.slice/docker-" + cid + ".scope",
+			want: ContainerInfo{Runtime: RuntimeDocker, ID: cid},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := ParseContainerInfo(tc.path)
+			assert.True(t, ok)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+
+	for _, path := range []string{"/", "/system.slice/sshd.service", "/user.slice/user-1000.slice/session-3.scope", "/lxc.monitor.web01"} {
+		_, ok := ParseContainerInfo(path)
+		assert.False(t, ok, path)
+	}
+}
+
+func TestGetContainerInfoOrder(t *testing.T) {
+	const (
+		cid1 = "b29faf21b7eff959f64b4192c34d5d67a707fe8561e9eaa608cb27693fba4242"
+		cid2 = "0dd6ac4bb3d4fc3cbb7b5e1c7c6f8b4d2ac1c3b5e4f6a7b8c9d0e1f2a3b4c5d6"
+	)
+	// cgroup v1 controllers can be in different cgroups, the first controller by name is used
+	paths := map[string]ControllerPath{
+		"memory":  {ControllerPath: "/docker/" + cid2},
+		"cpu":     {ControllerPath: "/docker/" + cid1},
+		"cpuacct": {ControllerPath: "/docker/" + cid2},
+		"blkio":   {ControllerPath: "/"},
+	}
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, ContainerInfo{Runtime: RuntimeDocker, ID: cid1}, getContainerInfo(paths))
+	}
+}
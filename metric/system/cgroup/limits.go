@@ -0,0 +1,295 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cgroup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/elastic-agent-system-metrics/metric/system/cgroup/cgcommon"
+)
+
+// unlimitedV1 is the lowest cgroup v1 memory limit that means no limit is set.
+// The kernel reports its maximum page count in bytes, which depends on the page size.
+const unlimitedV1 = 1 << 62
+
+// MemoryLimit returns the memory limit in bytes that applies to the cgroup,
+// the lower of its own limit and the limits of its parents.
+// The second return value is false if no limit is set.
+func (stat StatsV1) MemoryLimit() (uint64, bool) {
+	if stat.Memory == nil {
+		return 0, false
+	}
+	var limit uint64
+	for _, value := range []uint64{stat.Memory.Mem.Limit.Bytes, stat.Memory.Stats.HierarchicalMemoryLimit.Bytes} {
+		if value == 0 || value >= unlimitedV1 {
+			continue
+		}
+		if limit == 0 || value < limit {
+			limit = value
+		}
+	}
+	return limit, limit != 0
+}
+
+// CPULimit returns the number of CPUs the cgroup may use, the lowest of the CFS bandwidth quotas
+// of the cgroup and its parents, and of the number of CPUs in its effective cpuset.
+// The second return value is false if no limit is set.
+func (stat StatsV1) CPULimit() (float64, bool) {
+	limit := stat.limits.cpu
+	if stat.CPU != nil && stat.CPU.CFS.QuotaMicros.Us != 0 && stat.CPU.CFS.PeriodMicros.Us != 0 {
+		limit = minLimit(limit, float64(stat.CPU.CFS.QuotaMicros.Us)/float64(stat.CPU.CFS.PeriodMicros.Us))
+	}
+	limit = minLimit(limit, float64(stat.limits.cpus))
+	return limit, limit != 0
+}
+
+// MemoryLimit returns the memory limit in bytes that applies to the cgroup,
+// the lowest memory.max of the cgroup and its parents.
+// The second return value is false if no limit is set.
+func (stat StatsV2) MemoryLimit() (uint64, bool) {
+	limit := stat.limits.memory
+	if stat.Memory != nil && stat.Memory.Mem.Max.Bytes.Exists() {
+		if own := stat.Memory.Mem.Max.Bytes.ValueOr(0); limit == 0 || own < limit {
+			limit = own
+		}
+	}
+	return limit, limit != 0
+}
+
+// CPULimit returns the number of CPUs the cgroup may use, the lowest of the cpu.max bandwidth limits
+// of the cgroup and its parents, and of the number of CPUs in its effective cpuset.
+// The second return value is false if no limit is set.
+func (stat StatsV2) CPULimit() (float64, bool) {
+	limit := stat.limits.cpu
+	if stat.CPU != nil && stat.CPU.Max.Quota.Exists() && stat.CPU.Max.Period.ValueOr(0) != 0 {
+		limit = minLimit(limit, float64(stat.CPU.Max.Quota.ValueOr(0))/float64(stat.CPU.Max.Period.ValueOr(0)))
+	}
+	limit = minLimit(limit, float64(stat.limits.cpus))
+	return limit, limit != 0
+}
+
+// CacheLimits caches the effective limits of cgroups by path, until the returned function is called.
+// Most processes share a few cgroups, so this avoids walking the same cgroup hierarchy for every process of a scan.
+func (r *Reader) CacheLimits() func() {
+	r.limitsCache.Lock()
+	r.limitsCache.cache = make(map[string]effectiveLimits)
+	r.limitsCache.Unlock()
+	return func() {
+		r.limitsCache.Lock()
+		r.limitsCache.cache = nil
+		r.limitsCache.Unlock()
+	}
+}
+
+// cachedLimits returns the cached effective limits of the cgroup at key, or reads them with get
+func (r *Reader) cachedLimits(key string, get func() (effectiveLimits, error)) (effectiveLimits, error) {
+	r.limitsCache.Lock()
+	limits, ok := r.limitsCache.cache[key]
+	r.limitsCache.Unlock()
+	if ok {
+		return limits, nil
+	}
+
+	limits, err := get()
+	if err != nil {
+		return limits, err
+	}
+	r.limitsCache.Lock()
+	if r.limitsCache.cache != nil {
+		r.limitsCache.cache[key] = limits
+	}
+	r.limitsCache.Unlock()
+	return limits, nil
+}
+
+// effectiveLimits are the limits that apply to a cgroup through its parents, zero values mean no limit.
+type effectiveLimits struct {
+	memory uint64
+	cpu    float64
+	// cpus is the number of CPUs in the effective cpuset, if it is smaller than the cpuset of the root cgroup
+	cpus int
+}
+
+// minLimit returns the lower of two limits, where zero means no limit
+func minLimit(a, b float64) float64 {
+	if a == 0 || (b != 0 && b < a) {
+		return b
+	}
+	return a
+}
+
+// cgroupAncestors returns the directories of a cgroup and all its parents, up to the root of the hierarchy
+func cgroupAncestors(path ControllerPath) (string, []string) {
+	root := strings.TrimSuffix(path.FullPath, path.ControllerPath)
+	dirs := []string{}
+	for cgPath := path.ControllerPath; ; cgPath = filepath.Dir(cgPath) {
+		dirs = append(dirs, filepath.Join(root, cgPath))
+		if cgPath == "/" || cgPath == "." {
+			break
+		}
+	}
+	return filepath.Clean(root), dirs
+}
+
+// getEffectiveLimitsV2 walks up the cgroup v2 hierarchy from path, as limits are often set on a parent,
+// such as the slice of a Kubernetes pod.
+func getEffectiveLimitsV2(path ControllerPath) (effectiveLimits, error) {
+	limits := effectiveLimits{}
+	root, dirs := cgroupAncestors(path)
+	for _, dir := range dirs {
+		memory, ok, err := readCgroupLimit(dir, "memory.max")
+		if err != nil {
+			return limits, err
+		}
+		if ok && (limits.memory == 0 || memory < limits.memory) {
+			limits.memory = memory
+		}
+
+		raw, ok, err := readCgroupFile(dir, "cpu.max")
+		if err != nil {
+			return limits, err
+		}
+		if quota, period, found := strings.Cut(raw, " "); ok && found && quota != "max" {
+			cpu, err := parseQuota(quota, period)
+			if err != nil {
+				return limits, fmt.Errorf("error parsing cpu.max in %s: %w", dir, err)
+			}
+			limits.cpu = minLimit(limits.cpu, cpu)
+		}
+	}
+
+	var err error
+	limits.cpus, err = getCpusetCount(root, dirs, "cpuset.cpus.effective")
+	return limits, err
+}
+
+// getEffectiveLimitsV1 walks up the cgroup v1 cpu and cpuset hierarchies.
+// The memory controller already reports the limit of the hierarchy.
+func getEffectiveLimitsV1(paths map[string]ControllerPath) (effectiveLimits, error) {
+	limits := effectiveLimits{}
+	if path, ok := paths[cpuStat]; ok {
+		_, dirs := cgroupAncestors(path)
+		for _, dir := range dirs {
+			quota, ok, err := readCgroupFile(dir, "cpu.cfs_quota_us")
+			if err != nil {
+				return limits, err
+			}
+			period, _, err := readCgroupFile(dir, "cpu.cfs_period_us")
+			if err != nil {
+				return limits, err
+			}
+			// a quota of -1 means no limit
+			if !ok || strings.HasPrefix(quota, "-") {
+				continue
+			}
+			cpu, err := parseQuota(quota, period)
+			if err != nil {
+				return limits, fmt.Errorf("error parsing CFS quota in %s: %w", dir, err)
+			}
+			limits.cpu = minLimit(limits.cpu, cpu)
+		}
+	}
+
+	if path, ok := paths[cpusetStat]; ok {
+		root, dirs := cgroupAncestors(path)
+		var err error
+		limits.cpus, err = getCpusetCount(root, dirs, "cpuset.effective_cpus")
+		if err != nil {
+			return limits, err
+		}
+	}
+	return limits, nil
+}
+
+// getCpusetCount returns the number of CPUs in the effective cpuset of a cgroup, or zero if the cgroup
+// may use all the CPUs of the root cgroup. Cgroups without the cpuset controller inherit the cpuset of their parent.
+func getCpusetCount(root string, dirs []string, name string) (int, error) {
+	total, ok, err := readCpusetCount(root, name)
+	if err != nil || !ok {
+		return 0, err
+	}
+	for _, dir := range dirs {
+		count, ok, err := readCpusetCount(dir, name)
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
+			continue
+		}
+		if count < total {
+			return count, nil
+		}
+		return 0, nil
+	}
+	return 0, nil
+}
+
+func readCpusetCount(dir, name string) (int, bool, error) {
+	raw, ok, err := readCgroupFile(dir, name)
+	if err != nil || !ok || raw == "" {
+		return 0, false, err
+	}
+	count, err := cgcommon.CountListEntries(raw)
+	if err != nil {
+		return 0, false, fmt.Errorf("error parsing %s in %s: %w", name, dir, err)
+	}
+	return count, true, nil
+}
+
+// parseQuota returns the number of CPUs of a CFS bandwidth quota and period, in microseconds
+func parseQuota(quota, period string) (float64, error) {
+	q, err := strconv.ParseUint(quota, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	p, err := strconv.ParseUint(period, 10, 64)
+	if err != nil || p == 0 {
+		return 0, fmt.Errorf("invalid period '%s'", period)
+	}
+	return float64(q) / float64(p), nil
+}
+
+// readCgroupLimit reads a limit in bytes, where "max" means no limit.
+// The second return value is false if no limit is set.
+func readCgroupLimit(dir, name string) (uint64, bool, error) {
+	raw, ok, err := readCgroupFile(dir, name)
+	if err != nil || !ok || raw == "max" {
+		return 0, false, err
+	}
+	value, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("error parsing %s in %s: %w", name, dir, err)
+	}
+	return value, true, nil
+}
+
+// readCgroupFile returns the trimmed contents of a cgroup file.
+// The second return value is false if the file doesn't exist, as not every cgroup has every file.
+func readCgroupFile(dir, name string) (string, bool, error) {
+	raw, err := os.ReadFile(filepath.Join(dir, name))
+	if os.IsNotExist(err) {
+		return "", false, nil
+	} else if err != nil {
+		return "", false, fmt.Errorf("error reading %s: %w", filepath.Join(dir, name), err)
+	}
+	return strings.TrimSpace(string(raw)), true, nil
+}
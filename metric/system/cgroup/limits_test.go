@@ -0,0 +1,189 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cgroup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-libs/opt"
+	"github.com/elastic/elastic-agent-system-metrics/metric/system/cgroup/cgv1"
+	"github.com/elastic/elastic-agent-system-metrics/metric/system/cgroup/cgv2"
+)
+
+func TestLimitsV1(t *testing.T) {
+	stats := StatsV1{}
+	_, ok := stats.MemoryLimit()
+	assert.False(t, ok)
+	_, ok = stats.CPULimit()
+	assert.False(t, ok)
+
+	// no limit on the cgroup, but one on its parent
+	stats.Memory = &cgv1.MemorySubsystem{}
+	stats.Memory.Mem.Limit.Bytes = 9223372036854771712
+	stats.Memory.Stats.HierarchicalMemoryLimit.Bytes = 1 << 30
+	limit, ok := stats.MemoryLimit()
+	assert.True(t, ok)
+	assert.Equal(t, uint64(1<<30), limit)
+
+	stats.Memory.Mem.Limit.Bytes = 512 << 20
+	limit, ok = stats.MemoryLimit()
+	assert.True(t, ok)
+	assert.Equal(t, uint64(512<<20), limit)
+
+	stats.Memory.Mem.Limit.Bytes = 9223372036854771712
+	stats.Memory.Stats.HierarchicalMemoryLimit.Bytes = 9223372036854771712
+	_, ok = stats.MemoryLimit()
+	assert.False(t, ok)
+
+	// a quota of -1 is read as zero
+	stats.CPU = &cgv1.CPUSubsystem{}
+	stats.CPU.CFS.PeriodMicros.Us = 100000
+	_, ok = stats.CPULimit()
+	assert.False(t, ok)
+
+	stats.CPU.CFS.QuotaMicros.Us = 250000
+	cpus, ok := stats.CPULimit()
+	assert.True(t, ok)
+	assert.Equal(t, 2.5, cpus)
+}
+
+func TestLimitsV2(t *testing.T) {
+	stats := StatsV2{
+		Memory: &cgv2.MemorySubsystem{},
+		CPU:    &cgv2.CPUSubsystem{Max: cgv2.CPUMax{Period: opt.UintWith(100000)}},
+	}
+	_, ok := stats.MemoryLimit()
+	assert.False(t, ok)
+	_, ok = stats.CPULimit()
+	assert.False(t, ok)
+
+	stats.Memory.Mem.Max.Bytes = opt.UintWith(1 << 30)
+	stats.CPU.Max.Quota = opt.UintWith(200000)
+	limit, ok := stats.MemoryLimit()
+	assert.True(t, ok)
+	assert.Equal(t, uint64(1<<30), limit)
+	cpus, ok := stats.CPULimit()
+	assert.True(t, ok)
+	assert.Equal(t, 2.0, cpus)
+}
+
+func TestEffectiveLimitsV2(t *testing.T) {
+	root := t.TempDir()
+	writeCgroupFiles(t, root, map[string]string{
+		"cpuset.cpus.effective":                                "0-7",
+		"kubepods.slice/memory.max":                            "1073741824",
+		"kubepods.slice/cpu.max":                               "150000 100000",
+		"kubepods.slice/pod.slice/memory.max":                  "max",
+		"kubepods.slice/pod.slice/cpu.max":                     "max 100000",
+		"kubepods.slice/pod.slice/cpuset.cpus.effective":       "0-1,4",
+		"kubepods.slice/pod.slice/container.scope/memory.max":  "2147483648",
+		"kubepods.slice/pod.slice/container.scope/cpu.max":     "max 100000",
+		"kubepods.slice/pod.slice/container.scope/memory.high": "max",
+	})
+
+	cgPath := "/kubepods.slice/pod.slice/container.scope"
+	limits, err := getEffectiveLimitsV2(ControllerPath{ControllerPath: cgPath, FullPath: root + cgPath, IsV2: true})
+	require.NoError(t, err)
+	assert.Equal(t, effectiveLimits{memory: 1 << 30, cpu: 1.5, cpus: 3}, limits)
+
+	stats := StatsV2{limits: limits}
+	memory, ok := stats.MemoryLimit()
+	assert.True(t, ok)
+	assert.Equal(t, uint64(1<<30), memory)
+	cpus, ok := stats.CPULimit()
+	assert.True(t, ok)
+	assert.Equal(t, 1.5, cpus)
+
+	// a cpuset as large as the root cpuset is no limit
+	cgPath = "/kubepods.slice"
+	limits, err = getEffectiveLimitsV2(ControllerPath{ControllerPath: cgPath, FullPath: root + cgPath, IsV2: true})
+	require.NoError(t, err)
+	assert.Equal(t, effectiveLimits{memory: 1 << 30, cpu: 1.5}, limits)
+}
+
+func TestEffectiveLimitsV1(t *testing.T) {
+	root := t.TempDir()
+	writeCgroupFiles(t, root, map[string]string{
+		"cpu/cpu.cfs_quota_us":                          "-1",
+		"cpu/cpu.cfs_period_us":                         "100000",
+		"cpu/docker/cpu.cfs_quota_us":                   "400000",
+		"cpu/docker/cpu.cfs_period_us":                  "100000",
+		"cpu/docker/container/cpu.cfs_quota_us":         "-1",
+		"cpu/docker/container/cpu.cfs_period_us":        "100000",
+		"cpuset/cpuset.effective_cpus":                  "0-3",
+		"cpuset/docker/cpuset.effective_cpus":           "0-3",
+		"cpuset/docker/container/cpuset.effective_cpus": "2",
+	})
+
+	cgPath := "/docker/container"
+	limits, err := getEffectiveLimitsV1(map[string]ControllerPath{
+		"cpu":    {ControllerPath: cgPath, FullPath: filepath.Join(root, "cpu") + cgPath},
+		"cpuset": {ControllerPath: cgPath, FullPath: filepath.Join(root, "cpuset") + cgPath},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, effectiveLimits{cpu: 4, cpus: 1}, limits)
+
+	cpus, ok := StatsV1{limits: limits}.CPULimit()
+	assert.True(t, ok)
+	assert.Equal(t, 1.0, cpus)
+}
+
+func writeCgroupFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content+"\n"), 0o644))
+	}
+}
+
+func TestCacheLimits(t *testing.T) {
+	r := &Reader{}
+	reads := 0
+	get := func() (effectiveLimits, error) {
+		reads++
+		return effectiveLimits{memory: uint64(reads)}, nil
+	}
+
+	// nothing is cached until caching is enabled
+	_, err := r.cachedLimits("/a", get)
+	require.NoError(t, err)
+	_, err = r.cachedLimits("/a", get)
+	require.NoError(t, err)
+	assert.Equal(t, 2, reads)
+
+	release := r.CacheLimits()
+	first, err := r.cachedLimits("/a", get)
+	require.NoError(t, err)
+	cached, err := r.cachedLimits("/a", get)
+	require.NoError(t, err)
+	assert.Equal(t, first, cached)
+	_, err = r.cachedLimits("/b", get)
+	require.NoError(t, err)
+	assert.Equal(t, 4, reads)
+
+	release()
+	_, err = r.cachedLimits("/a", get)
+	require.NoError(t, err)
+	assert.Equal(t, 5, reads)
+}
@@ -41,16 +41,22 @@ type StatsV1 struct {
 	Memory        *cgv1.MemorySubsystem        `json:"memory,omitempty" struct:"memory,omitempty"`
 	BlockIO       *cgv1.BlockIOSubsystem       `json:"blkio,omitempty" struct:"blkio,omitempty"`
 	Version       CgroupsVersion               `json:"cgroups_version,omitempty" struct:"cgroups_version,omitempty"`
+	Container     ContainerInfo                `json:"container,omitempty" struct:"container,omitempty"` // Container and pod the cgroup belongs to.
+
+	limits effectiveLimits
 }
 
 // StatsV2 contains metrics and limits from each of the cgroup subsystems.
 type StatsV2 struct {
-	ID      string                `json:"id,omitempty"`   // ID of the cgroup.
-	Path    string                `json:"path,omitempty"` // Path to the cgroup relative to the cgroup subsystem's mountpoint.
-	CPU     *cgv2.CPUSubsystem    `json:"cpu,omitempty" struct:"cpu,omitempty"`
-	Memory  *cgv2.MemorySubsystem `json:"memory,omitempty" struct:"memory,omitempty"`
-	IO      *cgv2.IOSubsystem     `json:"io,omitempty" struct:"io,omitempty"`
-	Version CgroupsVersion        `json:"cgroups_version,omitempty" struct:"cgroups_version,omitempty"`
+	ID        string                `json:"id,omitempty"`   // ID of the cgroup.
+	Path      string                `json:"path,omitempty"` // Path to the cgroup relative to the cgroup subsystem's mountpoint.
+	CPU       *cgv2.CPUSubsystem    `json:"cpu,omitempty" struct:"cpu,omitempty"`
+	Memory    *cgv2.MemorySubsystem `json:"memory,omitempty" struct:"memory,omitempty"`
+	IO        *cgv2.IOSubsystem     `json:"io,omitempty" struct:"io,omitempty"`
+	Version   CgroupsVersion        `json:"cgroups_version,omitempty" struct:"cgroups_version,omitempty"`
+	Container ContainerInfo         `json:"container,omitempty" struct:"container,omitempty"` // Container and pod the cgroup belongs to.
+
+	limits effectiveLimits
 }
 
 // CgroupsVersion is a version tag that defines what version of cgroups is attached to a process
@@ -66,6 +72,7 @@ const (
 	blkioStat   = "blkio"
 	cpuAcctStat = "cpuacct"
 	cpuStat     = "cpu"
+	cpusetStat  = "cpuset"
 	ioStat      = "io"
 	memoryStat  = "memory"
 )
@@ -90,6 +97,12 @@ type pathCache struct {
 	cache map[string]pathListWithTime
 }
 
+// limitsCache maps cgroup paths to their effective limits, while caching is enabled with CacheLimits.
+type limitsCache struct {
+	sync.Mutex
+	cache map[string]effectiveLimits
+}
+
 // Reader reads cgroup metrics and limits.
 type Reader struct {
 	// Mountpoint of the root filesystem. Defaults to / if not set. This can be
@@ -101,6 +114,8 @@ type Reader struct {
 
 	// Cache to map known v2 cgroup controllerPaths to pathListWithTime.
 	v2ControllerPathCache pathCache
+	// Cache of the effective limits of cgroups, only set during CacheLimits.
+	limitsCache limitsCache
 }
 
 // ReaderOptions holds options for NewReaderOptions.
@@ -214,15 +229,22 @@ func (r *Reader) GetV1StatsForProcess(pid int) (*StatsV1, error) { //nolint: dup
 	stats := StatsV1{}
 	stats.Path, stats.ID = getCommonCgroupMetadata(paths.V1, r.ignoreRootCgroups)
 	stats.Version = CgroupsV1
+	stats.Container = getContainerInfo(paths.V1)
 	for conName, cgPath := range paths.V1 {
 		if r.ignoreRootCgroups && (cgPath.ControllerPath == "/" && r.cgroupsHierarchyOverride != cgPath.ControllerPath) {
 			continue
 		}
-		err := getStatsV1(cgPath, conName, &stats)
+		err := getStatsV1(cgPath, conName, r.rootfsMountpoint, &stats)
 		if err != nil {
 			return nil, fmt.Errorf("error fetching stats for controller %s: %w", conName, err)
 		}
 	}
+	stats.limits, err = r.cachedLimits(paths.V1[cpuStat].FullPath+":"+paths.V1[cpusetStat].FullPath, func() (effectiveLimits, error) {
+		return getEffectiveLimitsV1(paths.V1)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching effective limits: %w", err)
+	}
 
 	return &stats, nil
 }
@@ -237,6 +259,7 @@ func (r *Reader) GetV2StatsForProcess(pid int) (*StatsV2, error) { //nolint: dup
 	stats := StatsV2{}
 	stats.Path, stats.ID = getCommonCgroupMetadata(paths.V2, r.ignoreRootCgroups)
 	stats.Version = CgroupsV2
+	stats.Container = getContainerInfo(paths.V2)
 	for conName, cgPath := range paths.V2 {
 		if r.ignoreRootCgroups && (cgPath.ControllerPath == "/" && r.cgroupsHierarchyOverride != cgPath.ControllerPath) {
 			continue
@@ -246,6 +269,16 @@ func (r *Reader) GetV2StatsForProcess(pid int) (*StatsV2, error) { //nolint: dup
 			return nil, fmt.Errorf("error fetching stats for controller %s: %w", conName, err)
 		}
 	}
+	// all controllers share the same cgroup in v2
+	for _, cgPath := range paths.V2 {
+		stats.limits, err = r.cachedLimits(cgPath.FullPath, func() (effectiveLimits, error) {
+			return getEffectiveLimitsV2(cgPath)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error fetching effective limits: %w", err)
+		}
+		break
+	}
 	return &stats, nil
 }
 
@@ -292,7 +325,7 @@ func getStatsV2(path ControllerPath, name string, stats *StatsV2) error {
 	return nil
 }
 
-func getStatsV1(path ControllerPath, name string, stats *StatsV1) error {
+func getStatsV1(path ControllerPath, name string, hostfs resolve.Resolver, stats *StatsV1) error {
 	id := filepath.Base(path.ControllerPath)
 
 	switch name {
@@ -314,7 +347,7 @@ func getStatsV1(path ControllerPath, name string, stats *StatsV1) error {
 		stats.CPU.Path = path.ControllerPath
 	case cpuAcctStat:
 		stats.CPUAccounting = &cgv1.CPUAccountingSubsystem{}
-		err := stats.CPUAccounting.Get(path.FullPath)
+		err := stats.CPUAccounting.GetWithHostfs(path.FullPath, hostfs)
 		if err != nil {
 			return fmt.Errorf("error fetching cpuacct stats: %w", err)
 		}
@@ -77,6 +77,7 @@ func TestReaderGetStatsV1(t *testing.T) {
 	require.Equal(t, path, stats.CPUAccounting.Path)
 	require.Equal(t, path, stats.Memory.Path)
 
+	require.Equal(t, ContainerInfo{Runtime: RuntimeDocker, ID: id}, stats.Container)
 }
 
 func TestReaderGetStatsV2(t *testing.T) {
@@ -92,6 +93,7 @@ func TestReaderGetStatsV2(t *testing.T) {
 
 	require.Equal(t, pathv2, stats.Path)
 	require.Equal(t, idv2, stats.ID)
+	require.Equal(t, ContainerInfo{Runtime: RuntimeDocker, ID: "1c8fa019edd4b9d4b2856f4932c55929c5c118c808ed5faee9a135ca6e84b039"}, stats.Container)
 
 	require.NotZero(t, stats.CPU.Stats.Usage.NS)
 	require.NotZero(t, stats.Memory.Mem.Usage.Bytes)
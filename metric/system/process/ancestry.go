@@ -0,0 +1,160 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build (darwin && cgo) || freebsd || linux || windows || aix
+
+package process
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+)
+
+// maxAncestors bounds the walk up the parent chain, in case the chain loops because of reused PIDs
+const maxAncestors = 256
+
+// EntityID returns an identifier for a process that is unique across hosts and PID reuse,
+// for the ECS process.entity_id field. It is derived from the host ID, the PID and the start time.
+func EntityID(hostID string, pid int, startTime string) string {
+	sum := sha256.Sum256([]byte(hostID + "|" + strconv.Itoa(pid) + "|" + startTime))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// ancestryResolver looks up the ancestors of processes during a single fetch.
+// Ancestors that were not collected themselves are read with GetInfoForPid, and cached.
+type ancestryResolver struct {
+	procStats *Stats
+	procs     ProcsMap
+	hostID    string
+	looked    map[int]ProcState
+	isInit    map[int]bool
+}
+
+func (procStats *Stats) newAncestryResolver(procs ProcsMap) *ancestryResolver {
+	resolver := &ancestryResolver{
+		procStats: procStats,
+		procs:     procs,
+		looked:    map[int]ProcState{},
+		isInit:    map[int]bool{},
+	}
+	if procStats.host != nil {
+		resolver.hostID = procStats.host.Info().UniqueID
+	}
+	// without a host ID, entity IDs would collide across hosts
+	if resolver.hostID == "" && !procStats.noHostIDLogged {
+		procStats.logger.Warnf("no host ID is available, process.entity_id will not be reported")
+		procStats.noHostIDLogged = true
+	}
+	return resolver
+}
+
+// entityID returns the entity ID of proc, or an empty string if no host ID or start time is available.
+// Without a start time, the entity ID would collide when the PID is reused.
+func (ar *ancestryResolver) entityID(proc ProcState) string {
+	if ar.hostID == "" || proc.CPU.StartTime == "" {
+		return ""
+	}
+	return EntityID(ar.hostID, proc.Pid.ValueOr(0), proc.CPU.StartTime)
+}
+
+// lookup returns the state of pid from the current fetch, or from /proc if it was not collected
+func (ar *ancestryResolver) lookup(pid int) (ProcState, bool) {
+	if proc, ok := ar.procs[pid]; ok {
+		return proc, true
+	}
+	if proc, ok := ar.looked[pid]; ok {
+		return proc, proc.Pid.Exists()
+	}
+	proc, err := GetInfoForPid(ar.procStats.Hostfs, pid)
+	if err != nil {
+		ar.procStats.logger.Debugf("error fetching ancestor pid %d: %s", pid, err)
+		proc = ProcState{}
+	}
+	ar.looked[pid] = proc
+	return proc, proc.Pid.Exists()
+}
+
+// isContainerInit returns true if pid is the init process of a nested PID namespace
+func (ar *ancestryResolver) isContainerInit(pid int) bool {
+	if nsInit, ok := ar.isInit[pid]; ok {
+		return nsInit
+	}
+	nsInit, err := isNamespaceInit(ar.procStats.Hostfs, pid)
+	if err != nil {
+		ar.procStats.logger.Debugf("error checking if pid %d is a container init: %s", pid, err)
+	}
+	ar.isInit[pid] = nsInit
+	return nsInit
+}
+
+func (ar *ancestryResolver) ref(proc ProcState) ProcessRef {
+	return ProcessRef{
+		Pid:      proc.Pid,
+		Name:     proc.Name,
+		Start:    proc.CPU.StartTime,
+		EntityID: ar.entityID(proc),
+	}
+}
+
+// fill sets the entity ID and ancestry of proc.
+// The entry leader is the ancestor right below PID 1, or the container init if the process runs in a container.
+func (ar *ancestryResolver) fill(proc ProcState) ProcState {
+	pid := proc.Pid.ValueOr(0)
+	proc.EntityID = ar.entityID(proc)
+	ancestry := ProcAncestry{}
+
+	// the process itself is the last candidate for the entry leader
+	entry := proc
+	cur := proc
+	reachedInit := pid == 1 || ar.isContainerInit(pid)
+	for i := 0; i < maxAncestors && !reachedInit; i++ {
+		ppid := cur.Ppid.ValueOr(0)
+		if ppid <= 0 || ppid == cur.Pid.ValueOr(0) {
+			break
+		}
+		parent, ok := ar.lookup(ppid)
+		if !ok {
+			break
+		}
+		ancestry.Ancestors = append(ancestry.Ancestors, ar.ref(parent))
+		switch {
+		case ppid == 1:
+			reachedInit = true
+		case ar.isContainerInit(ppid):
+			reachedInit = true
+			entry = parent
+		default:
+			entry = parent
+		}
+		cur = parent
+	}
+	if reachedInit {
+		ancestry.EntryLeader = ar.ref(entry)
+	}
+
+	if session := proc.Session.ValueOr(0); session > 0 {
+		if session == pid {
+			ancestry.SessionLeader = ar.ref(proc)
+		} else if leader, ok := ar.lookup(session); ok {
+			ancestry.SessionLeader = ar.ref(leader)
+		}
+	}
+
+	proc.Ancestry = ancestry
+	return proc
+}
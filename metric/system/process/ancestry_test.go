@@ -0,0 +1,157 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build darwin || freebsd || linux || windows
+
+package process
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/opt"
+	"github.com/elastic/elastic-agent-system-metrics/metric/system/resolve"
+)
+
+// ancestryStart is the start time of every process in the ancestry tests.
+var ancestryStart = time.Date(2023, 5, 24, 12, 0, 0, 0, time.UTC)
+
+func testAncestryResolver(t *testing.T, procs ProcsMap) *ancestryResolver {
+	stats := &Stats{
+		// nothing can be read from an empty hostfs, so only the processes in procs are known
+		Hostfs: resolve.NewTestResolver(t.TempDir()),
+		logger: logp.NewLogger("test"),
+	}
+	resolver := stats.newAncestryResolver(procs)
+	resolver.hostID = "host-1"
+	return resolver
+}
+
+func TestAncestry(t *testing.T) {
+	// 1 systemd
+	// └── 500 sshd
+	//     └── 600 sshd (session 600)
+	//         └── 601 bash
+	//             └── 700 top
+	procs := ProcsMap{
+		1:   testProc{pid: 1, session: 1, name: "systemd", start: ancestryStart}.state(),
+		500: testProc{pid: 500, ppid: 1, session: 500, name: "sshd", start: ancestryStart}.state(),
+		600: testProc{pid: 600, ppid: 500, session: 600, name: "sshd", start: ancestryStart}.state(),
+		601: testProc{pid: 601, ppid: 600, session: 600, name: "bash", start: ancestryStart}.state(),
+		700: testProc{pid: 700, ppid: 601, session: 600, name: "top", start: ancestryStart}.state(),
+	}
+	resolver := testAncestryResolver(t, procs)
+
+	got := resolver.fill(procs[700])
+	assert.Equal(t, EntityID("host-1", 700, "2023-05-24T12:00:00.000Z"), got.EntityID)
+
+	var pids []int
+	for _, ancestor := range got.Ancestry.Ancestors {
+		pids = append(pids, ancestor.Pid.ValueOr(0))
+	}
+	assert.Equal(t, []int{601, 600, 500, 1}, pids)
+	assert.Equal(t, "bash", got.Ancestry.Ancestors[0].Name)
+	assert.Equal(t, EntityID("host-1", 601, "2023-05-24T12:00:00.000Z"), got.Ancestry.Ancestors[0].EntityID)
+	assert.Equal(t, opt.IntWith(600), got.Ancestry.SessionLeader.Pid)
+	assert.Equal(t, opt.IntWith(500), got.Ancestry.EntryLeader.Pid)
+
+	// a child of init is its own entry leader
+	got = resolver.fill(procs[500])
+	assert.Equal(t, opt.IntWith(500), got.Ancestry.EntryLeader.Pid)
+	assert.Equal(t, opt.IntWith(500), got.Ancestry.SessionLeader.Pid)
+
+	ancestry := got.Ancestry
+	root := got.FormatForRoot()
+	assert.Equal(t, "systemd", root.Process.Parent.Name)
+	assert.Equal(t, ancestry.Ancestors, root.Process.Ancestors)
+	assert.Equal(t, ancestry.EntryLeader, root.Process.EntryLeader)
+	assert.NotEmpty(t, root.Process.EntityID)
+	assert.True(t, got.Ancestry.IsZero(), "ancestry was not moved to the root event")
+}
+
+func TestAncestryContainer(t *testing.T) {
+	// 1 systemd
+	// └── 900 containerd-shim
+	//     └── 910 tini (PID 1 in the container)
+	//         └── 920 nginx
+	procs := ProcsMap{
+		1:   testProc{pid: 1, session: 1, name: "systemd", start: ancestryStart}.state(),
+		900: testProc{pid: 900, ppid: 1, session: 900, name: "containerd-shim", start: ancestryStart}.state(),
+		910: testProc{pid: 910, ppid: 900, session: 910, name: "tini", start: ancestryStart}.state(),
+		920: testProc{pid: 920, ppid: 910, session: 910, name: "nginx", start: ancestryStart}.state(),
+	}
+	resolver := testAncestryResolver(t, procs)
+	resolver.isInit[910] = true
+
+	got := resolver.fill(procs[920])
+	require.Len(t, got.Ancestry.Ancestors, 1)
+	assert.Equal(t, opt.IntWith(910), got.Ancestry.Ancestors[0].Pid)
+	assert.Equal(t, opt.IntWith(910), got.Ancestry.EntryLeader.Pid)
+}
+
+func TestAncestryBrokenChain(t *testing.T) {
+	// the parent exited before it could be looked up
+	procs := ProcsMap{
+		1:   testProc{pid: 1, session: 1, name: "systemd", start: ancestryStart}.state(),
+		300: testProc{pid: 300, ppid: 200, session: 300, name: "orphan", start: ancestryStart}.state(),
+	}
+	got := testAncestryResolver(t, procs).fill(procs[300])
+	assert.Empty(t, got.Ancestry.Ancestors)
+	assert.True(t, got.Ancestry.EntryLeader.IsZero())
+}
+
+func TestAncestryNoHostID(t *testing.T) {
+	procs := ProcsMap{
+		1:   testProc{pid: 1, session: 1, name: "systemd", start: ancestryStart}.state(),
+		500: testProc{pid: 500, ppid: 1, session: 500, name: "sshd", start: ancestryStart}.state(),
+	}
+	resolver := testAncestryResolver(t, procs)
+	resolver.hostID = ""
+
+	got := resolver.fill(procs[500])
+	assert.Empty(t, got.EntityID)
+	require.Len(t, got.Ancestry.Ancestors, 1)
+	assert.Equal(t, opt.IntWith(1), got.Ancestry.Ancestors[0].Pid)
+	assert.Empty(t, got.Ancestry.Ancestors[0].EntityID)
+	assert.Empty(t, got.Ancestry.EntryLeader.EntityID)
+}
+
+func TestAncestryNoStartTime(t *testing.T) {
+	procs := ProcsMap{
+		1:   testProc{pid: 1, session: 1, name: "systemd", start: ancestryStart}.state(),
+		500: testProc{pid: 500, ppid: 1, session: 500, name: "sshd", start: ancestryStart}.state(),
+	}
+	parent := procs[1]
+	parent.CPU.StartTime = ""
+	procs[1] = parent
+
+	got := testAncestryResolver(t, procs).fill(procs[500])
+	assert.NotEmpty(t, got.EntityID)
+	require.Len(t, got.Ancestry.Ancestors, 1)
+	assert.Empty(t, got.Ancestry.Ancestors[0].EntityID)
+}
+
+func TestEntityID(t *testing.T) {
+	id := EntityID("host-1", 42, "2023-05-24T12:00:00.000Z")
+	assert.Equal(t, id, EntityID("host-1", 42, "2023-05-24T12:00:00.000Z"))
+	assert.NotEqual(t, id, EntityID("host-2", 42, "2023-05-24T12:00:00.000Z"))
+	assert.NotEqual(t, id, EntityID("host-1", 42, "2023-05-24T12:00:01.000Z"))
+}
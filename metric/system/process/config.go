@@ -23,4 +23,7 @@ type IncludeTopConfig struct {
 	Enabled  bool `config:"enabled"`
 	ByCPU    int  `config:"by_cpu"`
 	ByMemory int  `config:"by_memory"`
+	// ByIO ranks processes by the combined read and write rate to storage.
+	// This requires Stats.EnableIO.
+	ByIO int `config:"by_io"`
 }
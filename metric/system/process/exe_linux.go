@@ -0,0 +1,80 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package process
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/elastic-agent-system-metrics/metric/system/resolve"
+)
+
+// getExeState compares the executable a process is running, from /proc/[PID]/exe,
+// with the file found at the same path inside the root directory of the process.
+func getExeState(hostfs resolve.Resolver, pid int) (ProcExeState, error) {
+	state := ProcExeState{}
+	exeLink := hostfs.Join("proc", strconv.Itoa(pid), "exe")
+	target, err := os.Readlink(exeLink)
+	// kernel threads have no executable
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	} else if err != nil {
+		return state, fmt.Errorf("error reading exe link for pid %d: %w", pid, err)
+	}
+
+	path, deleted, memoryBacked := parseExeLink(target)
+	if memoryBacked {
+		state.MemoryBacked = true
+		return state, nil
+	}
+	if deleted {
+		state.Deleted = true
+		return state, nil
+	}
+
+	running, err := os.Stat(exeLink)
+	if err != nil {
+		return state, fmt.Errorf("error fetching executable of pid %d: %w", pid, err)
+	}
+	// resolve the path the way the process sees it, which matters for processes in another mount namespace
+	onDisk, err := os.Stat(hostfs.Join("proc", strconv.Itoa(pid), "root", path))
+	if errors.Is(err, os.ErrNotExist) {
+		state.InodeMismatch = true
+		return state, nil
+	} else if err != nil {
+		return state, fmt.Errorf("error fetching %s for pid %d: %w", path, pid, err)
+	}
+	state.InodeMismatch = !os.SameFile(running, onDisk)
+
+	return state, nil
+}
+
+// parseExeLink splits the target of a /proc/[PID]/exe link into the executable path,
+// whether the file was deleted, and whether it is backed by memory rather than a file on disk.
+func parseExeLink(target string) (string, bool, bool) {
+	path, deleted := strings.CutSuffix(target, " (deleted)")
+	memoryBacked := strings.HasPrefix(path, "/memfd:") ||
+		strings.HasPrefix(path, "anon_inode:") ||
+		path == "/anon_hugepage"
+	return path, deleted, memoryBacked
+}
@@ -0,0 +1,103 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package process
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-system-metrics/metric/system/resolve"
+)
+
+func TestGetExeState(t *testing.T) {
+	sleep, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("sleep not found")
+	}
+	data, err := os.ReadFile(sleep)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "sleep")
+	require.NoError(t, os.WriteFile(path, data, 0o755))
+
+	cmd := exec.Command(path, "60")
+	require.NoError(t, cmd.Start())
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+
+	got, err := getExeState(resolve.NewTestResolver("/"), cmd.Process.Pid)
+	require.NoError(t, err)
+	assert.Equal(t, ProcExeState{}, got)
+
+	// replace the binary the way a package upgrade does
+	require.NoError(t, os.WriteFile(path+".new", data, 0o755))
+	require.NoError(t, os.Rename(path+".new", path))
+
+	got, err = getExeState(resolve.NewTestResolver("/"), cmd.Process.Pid)
+	require.NoError(t, err)
+	assert.Equal(t, ProcExeState{Deleted: true}, got)
+	assert.True(t, got.Anomalous())
+}
+
+func TestParseExeLink(t *testing.T) {
+	path, deleted, memoryBacked := parseExeLink("/usr/sbin/sshd")
+	assert.Equal(t, "/usr/sbin/sshd", path)
+	assert.False(t, deleted)
+	assert.False(t, memoryBacked)
+
+	path, deleted, memoryBacked = parseExeLink("/usr/sbin/sshd (deleted)")
+	assert.Equal(t, "/usr/sbin/sshd", path)
+	assert.True(t, deleted)
+	assert.False(t, memoryBacked)
+
+	_, deleted, memoryBacked = parseExeLink("/memfd:payload (deleted)")
+	assert.True(t, deleted)
+	assert.True(t, memoryBacked)
+}
+
+func TestGetExeStateInodeMismatch(t *testing.T) {
+	exe := filepath.Join(t.TempDir(), "agent")
+	require.NoError(t, os.WriteFile(exe, []byte("running"), 0o755))
+
+	// the process runs exe, while a different file is found at the same path from its root directory
+	hostfs := t.TempDir()
+	procDir := filepath.Join(hostfs, "proc", "42")
+	require.NoError(t, os.MkdirAll(filepath.Join(procDir, "root", filepath.Dir(exe)), 0o755))
+	require.NoError(t, os.Symlink(exe, filepath.Join(procDir, "exe")))
+	require.NoError(t, os.WriteFile(filepath.Join(procDir, "root", exe), []byte("replaced"), 0o755))
+
+	got, err := getExeState(resolve.NewTestResolver(hostfs), 42)
+	require.NoError(t, err)
+	assert.Equal(t, ProcExeState{InodeMismatch: true}, got)
+
+	// the same file is found when the root directory is the host root
+	require.NoError(t, os.RemoveAll(filepath.Join(procDir, "root")))
+	require.NoError(t, os.Symlink("/", filepath.Join(procDir, "root")))
+
+	got, err = getExeState(resolve.NewTestResolver(hostfs), 42)
+	require.NoError(t, err)
+	assert.Equal(t, ProcExeState{}, got)
+}
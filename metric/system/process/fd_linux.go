@@ -0,0 +1,153 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package process
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/elastic-agent-libs/opt"
+	"github.com/elastic/elastic-agent-system-metrics/metric/system/resolve"
+)
+
+// fdType is the classification of a single file descriptor, based on its link target
+type fdType int
+
+const (
+	fdFile fdType = iota
+	fdSocket
+	fdPipe
+	fdDevice
+	fdDeleted
+	fdMemFD
+	fdEventFD
+	fdEpoll
+	fdInotify
+	fdTimerFD
+	fdAnonInode
+	fdOther
+)
+
+// getFDDetail reads the link target of every entry in /proc/[PID]/fd and counts them by type.
+// If topPaths is greater than zero, the topPaths most-referenced paths are also returned.
+func getFDDetail(hostfs resolve.Resolver, pid int, topPaths int) (FDTypes, []FDPathCount, error) {
+	counts := make(map[fdType]uint64)
+	paths := make(map[string]int)
+
+	pathFD := hostfs.Join("proc", strconv.Itoa(pid), "fd")
+	fds, err := os.ReadDir(pathFD)
+	if err != nil {
+		return FDTypes{}, nil, fmt.Errorf("error reading FD directory for pid %d: %w", pid, err)
+	}
+	for _, fd := range fds {
+		target, err := os.Readlink(hostfs.Join("proc", strconv.Itoa(pid), "fd", fd.Name()))
+		if errors.Is(err, os.ErrNotExist) { // the fd was closed after we listed the directory
+			continue
+		} else if err != nil {
+			return FDTypes{}, nil, fmt.Errorf("error reading FD %s for pid %d: %w", fd.Name(), pid, err)
+		}
+
+		kind := classifyFD(target)
+		counts[kind]++
+		if topPaths > 0 && (kind == fdFile || kind == fdDevice || kind == fdDeleted) {
+			paths[target]++
+		}
+	}
+
+	types := FDTypes{
+		File:      opt.UintWith(counts[fdFile]),
+		Socket:    opt.UintWith(counts[fdSocket]),
+		Pipe:      opt.UintWith(counts[fdPipe]),
+		Device:    opt.UintWith(counts[fdDevice]),
+		Deleted:   opt.UintWith(counts[fdDeleted]),
+		MemFD:     opt.UintWith(counts[fdMemFD]),
+		EventFD:   opt.UintWith(counts[fdEventFD]),
+		Epoll:     opt.UintWith(counts[fdEpoll]),
+		Inotify:   opt.UintWith(counts[fdInotify]),
+		TimerFD:   opt.UintWith(counts[fdTimerFD]),
+		AnonInode: opt.UintWith(counts[fdAnonInode]),
+		Other:     opt.UintWith(counts[fdOther]),
+	}
+
+	return types, topFDPaths(paths, topPaths), nil
+}
+
+// classifyFD returns the type of a file descriptor from the target of its /proc/[PID]/fd link.
+// See https://man7.org/linux/man-pages/man5/proc.5.html for the link formats.
+func classifyFD(target string) fdType {
+	switch {
+	case strings.HasPrefix(target, "socket:"):
+		return fdSocket
+	case strings.HasPrefix(target, "pipe:"):
+		return fdPipe
+	case strings.HasPrefix(target, "anon_inode:"):
+		switch strings.Trim(strings.TrimPrefix(target, "anon_inode:"), "[]") {
+		case "eventfd":
+			return fdEventFD
+		case "eventpoll":
+			return fdEpoll
+		case "inotify":
+			return fdInotify
+		case "timerfd":
+			return fdTimerFD
+		default:
+			return fdAnonInode
+		}
+	case !strings.HasPrefix(target, "/"):
+		// namespace links, such as net:[4026531840]
+		return fdOther
+	case strings.HasPrefix(target, "/memfd:"):
+		// memfd links always carry the " (deleted)" suffix, as they have no name in any filesystem
+		return fdMemFD
+	case strings.HasSuffix(target, " (deleted)"):
+		return fdDeleted
+	case strings.HasPrefix(target, "/dev/") && !strings.HasPrefix(target, "/dev/shm/") && !strings.HasPrefix(target, "/dev/mqueue/"):
+		return fdDevice
+	default:
+		return fdFile
+	}
+}
+
+// topFDPaths returns the n paths with the highest reference count
+func topFDPaths(paths map[string]int, n int) []FDPathCount {
+	if n <= 0 || len(paths) == 0 {
+		return nil
+	}
+
+	sorted := make([]FDPathCount, 0, len(paths))
+	for path, count := range paths {
+		sorted = append(sorted, FDPathCount{Path: path, Count: count})
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Count == sorted[j].Count {
+			return sorted[i].Path < sorted[j].Path
+		}
+		return sorted[i].Count > sorted[j].Count
+	})
+
+	if len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
@@ -0,0 +1,105 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package process
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-libs/opt"
+	"github.com/elastic/elastic-agent-system-metrics/metric/system/resolve"
+)
+
+func TestClassifyFD(t *testing.T) {
+	tests := map[string]fdType{
+		"socket:[4097383]":                  fdSocket,
+		"pipe:[4097380]":                    fdPipe,
+		"anon_inode:[eventfd]":              fdEventFD,
+		"anon_inode:[eventpoll]":            fdEpoll,
+		"anon_inode:inotify":                fdInotify,
+		"anon_inode:[timerfd]":              fdTimerFD,
+		"anon_inode:[signalfd]":             fdAnonInode,
+		"net:[4026531840]":                  fdOther,
+		"/dev/null":                         fdDevice,
+		"/dev/pts/0":                        fdDevice,
+		"/dev/shm/sem.lock":                 fdFile,
+		"/var/log/syslog":                   fdFile,
+		"/var/log/syslog.1 (deleted)":       fdDeleted,
+		"/memfd:jit-cache (deleted)":        fdMemFD,
+		"/usr/share/zoneinfo/UTC":           fdFile,
+		"/proc/4067478/task/4067480/status": fdFile,
+	}
+	for target, want := range tests {
+		assert.Equal(t, want, classifyFD(target), target)
+	}
+}
+
+func TestFDTypesMemFDOnly(t *testing.T) {
+	types := FDTypes{MemFD: opt.UintWith(1)}
+	assert.False(t, types.IsZero(), "memfds are dropped from fd.types")
+}
+
+func TestTopFDPaths(t *testing.T) {
+	paths := map[string]int{
+		"/var/log/a": 3,
+		"/var/log/b": 10,
+		"/var/log/c": 3,
+		"/dev/null":  1,
+	}
+	want := []FDPathCount{
+		{Path: "/var/log/b", Count: 10},
+		{Path: "/var/log/a", Count: 3},
+		{Path: "/var/log/c", Count: 3},
+	}
+	assert.Equal(t, want, topFDPaths(paths, 3))
+	assert.Len(t, topFDPaths(paths, 10), 4)
+	assert.Nil(t, topFDPaths(paths, 0))
+}
+
+func TestSelfFDDetail(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "shared")
+	for i := 0; i < 3; i++ {
+		f, err := os.Create(path)
+		require.NoError(t, err)
+		defer f.Close()
+	}
+	deleted, err := os.Create(filepath.Join(dir, "deleted"))
+	require.NoError(t, err)
+	defer deleted.Close()
+	require.NoError(t, os.Remove(deleted.Name()))
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	defer r.Close()
+	defer w.Close()
+
+	types, top, err := getFDDetail(resolve.NewTestResolver("/"), os.Getpid(), 1)
+	require.NoError(t, err)
+
+	assert.GreaterOrEqual(t, types.Pipe.ValueOr(0), uint64(2))
+	assert.GreaterOrEqual(t, types.File.ValueOr(0), uint64(3))
+	assert.GreaterOrEqual(t, types.Deleted.ValueOr(0), uint64(1))
+	require.Len(t, top, 1)
+	assert.Equal(t, FDPathCount{Path: path, Count: 3}, top[0])
+}
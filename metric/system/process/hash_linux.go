@@ -0,0 +1,189 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package process
+
+import (
+	"crypto/sha256"
+	"debug/elf"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"syscall"
+
+	"github.com/elastic/elastic-agent-system-metrics/metric/system/resolve"
+)
+
+// exeHashKey identifies a version of an executable file
+type exeHashKey struct {
+	dev   uint64
+	ino   uint64
+	mtime int64
+	size  int64
+}
+
+type exeHashEntry struct {
+	hash    ProcHash
+	buildID string
+	used    bool
+}
+
+// exeHashCache holds the hashes of the executables seen during recent scans of /proc,
+// so that each binary is only read once no matter how many processes run it.
+type exeHashCache struct {
+	mut     sync.Mutex
+	entries map[exeHashKey]*exeHashEntry
+	// executables larger than maxSize are not hashed, as they would stall the scan
+	maxSize int64
+}
+
+func newExeHashCache(maxSize int64) *exeHashCache {
+	return &exeHashCache{entries: map[exeHashKey]*exeHashEntry{}, maxSize: maxSize}
+}
+
+// expire drops the executables that weren't used since the last call, this should be called at the end of every scan.
+func (hc *exeHashCache) expire() {
+	hc.mut.Lock()
+	defer hc.mut.Unlock()
+	for key, entry := range hc.entries {
+		if !entry.used {
+			delete(hc.entries, key)
+			continue
+		}
+		entry.used = false
+	}
+}
+
+// getExeHash returns the SHA-256 hash and the GNU build ID of the executable of a process.
+// The file is read through /proc/[PID]/exe, which works for processes in other mount namespaces
+// and for executables that were deleted. Executables larger than the size limit of the cache
+// only get a build ID.
+func (hc *exeHashCache) getExeHash(hostfs resolve.Resolver, pid int) (ProcHash, string, error) {
+	path := hostfs.Join("proc", strconv.Itoa(pid), "exe")
+	f, err := os.Open(path)
+	// kernel threads have no executable
+	if errors.Is(err, os.ErrNotExist) {
+		return ProcHash{}, "", nil
+	} else if err != nil {
+		return ProcHash{}, "", fmt.Errorf("error opening executable of pid %d: %w", pid, err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return ProcHash{}, "", fmt.Errorf("error fetching executable of pid %d: %w", pid, err)
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return ProcHash{}, "", fmt.Errorf("unexpected stat type %T for executable of pid %d", info.Sys(), pid)
+	}
+	key := exeHashKey{
+		dev:   stat.Dev,
+		ino:   stat.Ino,
+		mtime: info.ModTime().UnixNano(),
+		size:  info.Size(),
+	}
+
+	hc.mut.Lock()
+	entry, ok := hc.entries[key]
+	if ok {
+		entry.used = true
+		hc.mut.Unlock()
+		return entry.hash, entry.buildID, nil
+	}
+	hc.mut.Unlock()
+
+	entry = &exeHashEntry{used: true}
+	if hc.maxSize <= 0 || info.Size() <= hc.maxSize {
+		hash := sha256.New()
+		if _, err := io.Copy(hash, f); err != nil {
+			return ProcHash{}, "", fmt.Errorf("error hashing executable of pid %d: %w", pid, err)
+		}
+		entry.hash = ProcHash{SHA256: hex.EncodeToString(hash.Sum(nil))}
+	}
+	// not every executable is an ELF file, such as scripts run through binfmt_misc
+	entry.buildID, _ = getBuildID(f)
+
+	hc.mut.Lock()
+	hc.entries[key] = entry
+	hc.mut.Unlock()
+
+	return entry.hash, entry.buildID, nil
+}
+
+// getBuildID reads the GNU build ID note of an ELF file, see the description of --build-id in ld(1)
+func getBuildID(r io.ReaderAt) (string, error) {
+	file, err := elf.NewFile(r)
+	if err != nil {
+		return "", fmt.Errorf("error reading ELF file: %w", err)
+	}
+	defer file.Close()
+
+	for _, prog := range file.Progs {
+		if prog.Type != elf.PT_NOTE {
+			continue
+		}
+		data, err := io.ReadAll(prog.Open())
+		if err != nil {
+			return "", fmt.Errorf("error reading ELF notes: %w", err)
+		}
+		if id, ok := findBuildIDNote(data, file.ByteOrder, prog.Align); ok {
+			return id, nil
+		}
+	}
+	return "", nil
+}
+
+// findBuildIDNote walks the notes of a PT_NOTE segment, see the "Note Section" chapter of elf(5)
+func findBuildIDNote(data []byte, order binary.ByteOrder, align uint64) (string, bool) {
+	// NT_GNU_BUILD_ID from elf.h
+	const ntGNUBuildID = 3
+	if align < 4 {
+		align = 4
+	}
+	pad := func(n uint64) uint64 {
+		return (n + align - 1) &^ (align - 1)
+	}
+
+	for uint64(len(data)) >= 12 {
+		nameSize := uint64(order.Uint32(data[0:4]))
+		descSize := uint64(order.Uint32(data[4:8]))
+		noteType := order.Uint32(data[8:12])
+		data = data[12:]
+
+		nameEnd := pad(nameSize)
+		descEnd := nameEnd + pad(descSize)
+		if nameEnd > uint64(len(data)) || nameEnd+descSize > uint64(len(data)) {
+			return "", false
+		}
+		if noteType == ntGNUBuildID && nameSize == 4 && string(data[:4]) == "GNU\x00" {
+			return hex.EncodeToString(data[nameEnd : nameEnd+descSize]), true
+		}
+		if descEnd > uint64(len(data)) {
+			return "", false
+		}
+		data = data[descEnd:]
+	}
+	return "", false
+}
@@ -0,0 +1,132 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package process
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-system-metrics/metric/system/resolve"
+)
+
+func TestGetExeHash(t *testing.T) {
+	exe, err := os.Executable()
+	require.NoError(t, err)
+	data, err := os.ReadFile(exe)
+	require.NoError(t, err)
+	sum := sha256.Sum256(data)
+
+	cache := newExeHashCache(0)
+	got, _, err := cache.getExeHash(resolve.NewTestResolver("/"), os.Getpid())
+	require.NoError(t, err)
+	assert.Equal(t, hex.EncodeToString(sum[:]), got.SHA256)
+	assert.Len(t, cache.entries, 1)
+
+	// a second lookup of the same file is served from the cache
+	got, _, err = cache.getExeHash(resolve.NewTestResolver("/"), os.Getpid())
+	require.NoError(t, err)
+	assert.Equal(t, hex.EncodeToString(sum[:]), got.SHA256)
+	assert.Len(t, cache.entries, 1)
+
+	cache.expire()
+	assert.Len(t, cache.entries, 1)
+	cache.expire()
+	assert.Empty(t, cache.entries)
+}
+
+func TestGetExeHashMaxSize(t *testing.T) {
+	cache := newExeHashCache(1)
+	got, buildID, err := cache.getExeHash(resolve.NewTestResolver("/"), os.Getpid())
+	require.NoError(t, err)
+	assert.Empty(t, got.SHA256, "executables above the size limit are not hashed")
+	// the build ID is read from the notes only
+	exe, err := os.Executable()
+	require.NoError(t, err)
+	f, err := os.Open(exe)
+	require.NoError(t, err)
+	defer f.Close()
+	want, _ := getBuildID(f)
+	assert.Equal(t, want, buildID)
+}
+
+func TestFormatForRootHash(t *testing.T) {
+	proc := ProcState{Hash: ProcHash{SHA256: "abc"}, BuildID: "def"}
+	root := proc.FormatForRoot()
+	assert.Equal(t, "abc", root.Process.Hash.SHA256)
+	assert.Zero(t, proc.Hash)
+	// the build ID is not an ECS field, and stays in the process event
+	assert.Equal(t, "def", proc.BuildID)
+}
+
+func TestGetBuildID(t *testing.T) {
+	sleep, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("sleep not found")
+	}
+	f, err := os.Open(sleep)
+	require.NoError(t, err)
+	defer f.Close()
+
+	id, err := getBuildID(f)
+	require.NoError(t, err)
+	if id == "" {
+		t.Skipf("%s has no build ID", sleep)
+	}
+	// build IDs are usually a SHA-1 hash
+	assert.Len(t, id, 40)
+}
+
+func TestFindBuildIDNote(t *testing.T) {
+	note := func(name string, noteType uint32, desc []byte) []byte {
+		buf := binary.LittleEndian.AppendUint32(nil, uint32(len(name)))
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(len(desc)))
+		buf = binary.LittleEndian.AppendUint32(buf, noteType)
+		buf = append(buf, name...)
+		for len(buf)%4 != 0 {
+			buf = append(buf, 0)
+		}
+		buf = append(buf, desc...)
+		for len(buf)%4 != 0 {
+			buf = append(buf, 0)
+		}
+		return buf
+	}
+
+	// an ABI tag note followed by the build ID
+	data := note("GNU\x00", 1, []byte{0, 0, 0, 0, 3, 0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0})
+	data = append(data, note("GNU\x00", 3, []byte{0xde, 0xad, 0xbe, 0xef, 0x01})...)
+	id, ok := findBuildIDNote(data, binary.LittleEndian, 4)
+	require.True(t, ok)
+	assert.Equal(t, "deadbeef01", id)
+
+	// the Go build ID is not a GNU build ID
+	_, ok = findBuildIDNote(note("Go\x00\x00", 4, []byte("abc")), binary.LittleEndian, 4)
+	assert.False(t, ok)
+
+	_, ok = findBuildIDNote(data[:20], binary.LittleEndian, 4)
+	assert.False(t, ok)
+}
@@ -19,6 +19,7 @@ package process
 
 import (
 	"math"
+	"sort"
 	"time"
 
 	"github.com/elastic/elastic-agent-libs/opt"
@@ -33,6 +34,18 @@ func unixTimeMsToTime(unixTimeMs uint64) string {
 	return typeconv.Time(time.Unix(0, int64(unixTimeMs*1000000))).String()
 }
 
+// getStartTime parses the start time of a process from the formatted CPU.StartTime value
+func getStartTime(proc ProcState) (time.Time, bool) {
+	if proc.CPU.StartTime == "" {
+		return time.Time{}, false
+	}
+	start, err := typeconv.ParseTime(proc.CPU.StartTime)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Time(start), true
+}
+
 func stripNullByte(buf []byte) string { //nolint: deadcode,unused,nolintlint // it is used in platform specific code
 	return string(buf[0 : len(buf)-1])
 }
@@ -52,6 +65,21 @@ func GetProcMemPercentage(proc ProcState, totalPhyMem uint64) opt.Float {
 	return opt.FloatWith(metric.Round(perc))
 }
 
+// GetProcCgroupPercentage fills out the memory and CPU percentages of proc relative to the limits of its cgroup.
+// memLimit is the memory limit in bytes, and cpuLimit the number of CPUs the process may use.
+// Percentages are left unset for limits that are zero.
+func GetProcCgroupPercentage(proc ProcState, memLimit uint64, cpuLimit float64) ProcState {
+	if memLimit > 0 && proc.Memory.Rss.Bytes.Exists() {
+		pct := float64(proc.Memory.Rss.Bytes.ValueOr(0)) / float64(memLimit)
+		proc.Memory.Rss.Cgroup = opt.PctOpt{Pct: opt.FloatWith(metric.Round(pct))}
+	}
+	if cpuLimit > 0 && proc.CPU.Total.Pct.Exists() {
+		pct := proc.CPU.Total.Pct.ValueOr(0) / cpuLimit
+		proc.CPU.Total.Cgroup = opt.PctOpt{Pct: opt.FloatWith(metric.Round(pct))}
+	}
+	return proc
+}
+
 // isProcessInSlice looks up proc in the processes slice and returns if
 // found or not
 func isProcessInSlice(processes []ProcState, proc *ProcState) bool {
@@ -75,26 +103,173 @@ func isProcessInSlice(processes []ProcState, proc *ProcState) bool {
 // available between samples. This could result in incorrect percentages if the
 // wall-clock is adjusted (prior to Go 1.9) or the machine is suspended.
 func GetProcCPUPercentage(s0, s1 ProcState) ProcState {
-	// Skip if we're missing the total ticks
-	if s0.CPU.Total.Ticks.IsZero() || s1.CPU.Total.Ticks.IsZero() {
+	return getProcCPUPercentage(s0, s1, numcpu.NumCPU())
+}
+
+// GetProcCPUPercentageByAffinity is the same as GetProcCPUPercentage, but the normalized
+// percentage is based on the number of CPUs the process is allowed to run on, if known.
+// A process pinned to 2 cores that keeps both busy is reported at 100%.
+func GetProcCPUPercentageByAffinity(s0, s1 ProcState) ProcState {
+	numCPU := s1.Placement.CPUsAllowedCount.ValueOr(0)
+	if numCPU <= 0 {
+		numCPU = numcpu.NumCPU()
+	}
+	return getProcCPUPercentage(s0, s1, numCPU)
+}
+
+func getProcCPUPercentage(s0, s1 ProcState, numCPU int) ProcState {
+	// s0 belongs to a process that previously held the PID
+	if !isSameProcess(s0, s1) {
 		return s1
 	}
+	s1.CPU = getCPUPercentage(s0.CPU, s1.CPU, s1.SampleTime.Sub(s0.SampleTime), numCPU)
+	return s1
+}
+
+// GetThreadCPUPercentage fills out the CPU percentages for each thread in s1
+// that was also present in the previous sample s0, using the same method as GetProcCPUPercentage.
+func GetThreadCPUPercentage(s0, s1 ProcState) ProcState {
+	if len(s0.Threads) == 0 || len(s1.Threads) == 0 {
+		return s1
+	}
+
+	prevThreads := make(map[int]ThreadState, len(s0.Threads))
+	for _, thread := range s0.Threads {
+		prevThreads[thread.Tid.ValueOr(0)] = thread
+	}
+
+	timeDelta := s1.SampleTime.Sub(s0.SampleTime)
+	for i, thread := range s1.Threads {
+		prev, ok := prevThreads[thread.Tid.ValueOr(0)]
+		if !ok {
+			continue
+		}
+		s1.Threads[i].CPU = getCPUPercentage(prev.CPU, thread.CPU, timeDelta, numcpu.NumCPU())
+	}
+
+	return s1
+}
+
+// GetProcIORate fills out the per-second I/O rates of s1 from the counters in the previous sample s0.
+func GetProcIORate(s0, s1 ProcState) ProcState {
+	timeDelta := s1.SampleTime.Sub(s0.SampleTime)
+
+	s1.IO.ReadChar = getCounterRate(s0.IO.ReadChar, s1.IO.ReadChar, timeDelta)
+	s1.IO.WriteChar = getCounterRate(s0.IO.WriteChar, s1.IO.WriteChar, timeDelta)
+	s1.IO.ReadSyscalls = getCounterRate(s0.IO.ReadSyscalls, s1.IO.ReadSyscalls, timeDelta)
+	s1.IO.WriteSyscalls = getCounterRate(s0.IO.WriteSyscalls, s1.IO.WriteSyscalls, timeDelta)
+	s1.IO.ReadBytes = getCounterRate(s0.IO.ReadBytes, s1.IO.ReadBytes, timeDelta)
+	s1.IO.WriteBytes = getCounterRate(s0.IO.WriteBytes, s1.IO.WriteBytes, timeDelta)
+	s1.IO.CancelledWriteBytes = getCounterRate(s0.IO.CancelledWriteBytes, s1.IO.CancelledWriteBytes, timeDelta)
+
+	return s1
+}
 
+// GetProcSchedRate fills out the per-second scheduler and page fault rates of s1 from the counters in the previous sample s0.
+func GetProcSchedRate(s0, s1 ProcState) ProcState {
 	timeDelta := s1.SampleTime.Sub(s0.SampleTime)
+
+	s1.Sched.VoluntaryCtxSwitches = getCounterRate(s0.Sched.VoluntaryCtxSwitches, s1.Sched.VoluntaryCtxSwitches, timeDelta)
+	s1.Sched.InvoluntaryCtxSwitches = getCounterRate(s0.Sched.InvoluntaryCtxSwitches, s1.Sched.InvoluntaryCtxSwitches, timeDelta)
+	s1.Sched.CPUTimeNS = getCounterRate(s0.Sched.CPUTimeNS, s1.Sched.CPUTimeNS, timeDelta)
+	s1.Sched.RunqueueWaitNS = getCounterRate(s0.Sched.RunqueueWaitNS, s1.Sched.RunqueueWaitNS, timeDelta)
+	s1.Sched.Timeslices = getCounterRate(s0.Sched.Timeslices, s1.Sched.Timeslices, timeDelta)
+	s1.Memory.PageFaults.Minor = getCounterRate(s0.Memory.PageFaults.Minor, s1.Memory.PageFaults.Minor, timeDelta)
+	s1.Memory.PageFaults.Major = getCounterRate(s0.Memory.PageFaults.Major, s1.Memory.PageFaults.Major, timeDelta)
+
+	return s1
+}
+
+// GetProcLimitUsage fills out the usage of the resource limits that have a matching metric,
+// as a fraction of the soft limit. The number of threads is compared against the process limit,
+// as each thread counts towards it.
+func GetProcLimitUsage(state ProcState) ProcState {
+	state.Limits.Processes.Pct = getLimitPct(state.Limits.Processes, uint64(state.NumThreads.ValueOr(0)))
+	state.Limits.OpenFiles.Pct = getLimitPct(state.Limits.OpenFiles, state.FD.Open.ValueOr(0))
+	state.Limits.AddressSpace.Pct = getLimitPct(state.Limits.AddressSpace, state.Memory.Size.ValueOr(0))
+	return state
+}
+
+func getLimitPct(limit ResourceLimit, used uint64) opt.Float {
+	soft := limit.Soft.ValueOr(0)
+	if soft == 0 || used == 0 {
+		return opt.NewFloatNone()
+	}
+	return opt.FloatWith(metric.Round(float64(used) / float64(soft)))
+}
+
+// RankByOOMScore returns the processes that the OOM killer may select, with the most likely candidate first.
+// Processes are ranked by their OOM score, and by resident memory for equal scores.
+// Processes without a score, and processes that are exempt with an oom_score_adj of -1000, are left out.
+func RankByOOMScore(procs []ProcState) []ProcState {
+	ranked := make([]ProcState, 0, len(procs))
+	for _, proc := range procs {
+		if !proc.OOM.Score.Exists() || proc.OOM.ScoreAdj.ValueOr(0) == -1000 {
+			continue
+		}
+		ranked = append(ranked, proc)
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		si, sj := ranked[i].OOM.Score.ValueOr(0), ranked[j].OOM.Score.ValueOr(0)
+		if si != sj {
+			return si > sj
+		}
+		return ranked[i].Memory.Rss.Bytes.ValueOr(0) > ranked[j].Memory.Rss.Bytes.ValueOr(0)
+	})
+	return ranked
+}
+
+// ListAnomalousExecutables returns the processes that are not running the file found at their executable path,
+// because it was deleted or replaced, or because the executable only exists in memory. The result is sorted by PID.
+func ListAnomalousExecutables(procs []ProcState) []ProcState {
+	var found []ProcState
+	for _, proc := range procs {
+		if proc.ExeState.Anomalous() {
+			found = append(found, proc)
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].Pid.ValueOr(0) < found[j].Pid.ValueOr(0)
+	})
+	return found
+}
+
+// getCounterRate fills out the per-second rate of c1 from the delta with c0 over timeDelta.
+func getCounterRate(c0, c1 Counter, timeDelta time.Duration) Counter {
+	// Skip if either sample is missing, or if the counter went backwards
+	if c0.Total.IsZero() || c1.Total.IsZero() || timeDelta <= 0 {
+		return c1
+	}
+	if c1.Total.ValueOr(0) < c0.Total.ValueOr(0) {
+		return c1
+	}
+
+	delta := c1.Total.ValueOr(0) - c0.Total.ValueOr(0)
+	c1.Rate = opt.FloatWith(metric.Round(float64(delta) / timeDelta.Seconds()))
+
+	return c1
+}
+
+// getCPUPercentage fills out the total CPU percentages of c1 from the tick delta with c0 over timeDelta.
+func getCPUPercentage(c0, c1 ProcCPUInfo, timeDelta time.Duration, numCPU int) ProcCPUInfo {
+	// Skip if we're missing the total ticks
+	if c0.Total.Ticks.IsZero() || c1.Total.Ticks.IsZero() {
+		return c1
+	}
+
 	timeDeltaDur := timeDelta / time.Millisecond
-	totalCPUDeltaMillis := int64(s1.CPU.Total.Ticks.ValueOr(0) - s0.CPU.Total.Ticks.ValueOr(0))
+	totalCPUDeltaMillis := int64(c1.Total.Ticks.ValueOr(0) - c0.Total.Ticks.ValueOr(0))
 
 	pct := float64(totalCPUDeltaMillis) / float64(timeDeltaDur)
 	// In theory this can only happen if the time delta is 0, which is unlikely but possible.
 	// With all the type conversion and non-integer math, this is probably the safest way to check.
 	if math.IsNaN(pct) {
-		return s1
+		return c1
 	}
-	normalizedPct := pct / float64(numcpu.NumCPU())
+	normalizedPct := pct / float64(numCPU)
 
-	s1.CPU.Total.Norm.Pct = opt.FloatWith(metric.Round(normalizedPct))
-	s1.CPU.Total.Pct = opt.FloatWith(metric.Round(pct))
-
-	return s1
+	c1.Total.Norm.Pct = opt.FloatWith(metric.Round(normalizedPct))
+	c1.Total.Pct = opt.FloatWith(metric.Round(pct))
 
+	return c1
 }
@@ -0,0 +1,70 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package process
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/elastic-agent-libs/opt"
+	"github.com/elastic/elastic-agent-system-metrics/metric/system/resolve"
+)
+
+// getIOData fetches the I/O counters for a process from /proc/[PID]/io
+func getIOData(hostfs resolve.Resolver, pid int) (ProcIOInfo, error) {
+	state := ProcIOInfo{}
+	path := hostfs.Join("proc", strconv.Itoa(pid), "io")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return state, fmt.Errorf("error opening file %s: %w", path, err)
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.SplitN(line, ":", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		value, err := strconv.ParseUint(strings.TrimSpace(fields[1]), 10, 64)
+		if err != nil {
+			return state, fmt.Errorf("error parsing %s value for pid %d: %w", fields[0], pid, err)
+		}
+
+		switch fields[0] {
+		case "rchar":
+			state.ReadChar.Total = opt.UintWith(value)
+		case "wchar":
+			state.WriteChar.Total = opt.UintWith(value)
+		case "syscr":
+			state.ReadSyscalls.Total = opt.UintWith(value)
+		case "syscw":
+			state.WriteSyscalls.Total = opt.UintWith(value)
+		case "read_bytes":
+			state.ReadBytes.Total = opt.UintWith(value)
+		case "write_bytes":
+			state.WriteBytes.Total = opt.UintWith(value)
+		case "cancelled_write_bytes":
+			state.CancelledWriteBytes.Total = opt.UintWith(value)
+		}
+	}
+
+	return state, nil
+}
@@ -0,0 +1,87 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package process
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-libs/opt"
+	"github.com/elastic/elastic-agent-system-metrics/metric/system/resolve"
+)
+
+func TestGetIOData(t *testing.T) {
+	got, err := getIOData(resolve.NewTestResolver("testdata"), 42)
+	require.NoError(t, err)
+
+	want := ProcIOInfo{
+		ReadChar:            Counter{Total: opt.UintWith(323934931)},
+		WriteChar:           Counter{Total: opt.UintWith(323929600)},
+		ReadSyscalls:        Counter{Total: opt.UintWith(632687)},
+		WriteSyscalls:       Counter{Total: opt.UintWith(632675)},
+		ReadBytes:           Counter{Total: opt.UintWith(4096)},
+		WriteBytes:          Counter{Total: opt.UintWith(323932160)},
+		CancelledWriteBytes: Counter{Total: opt.UintWith(0)},
+	}
+	assert.Equal(t, want, got)
+}
+
+func TestProcIORate(t *testing.T) {
+	s0 := ProcState{
+		IO: ProcIOInfo{
+			ReadBytes:  Counter{Total: opt.UintWith(1000)},
+			WriteBytes: Counter{Total: opt.UintWith(5000)},
+		},
+		SampleTime: time.Now(),
+	}
+	s1 := ProcState{
+		IO: ProcIOInfo{
+			ReadBytes:  Counter{Total: opt.UintWith(21000)},
+			WriteBytes: Counter{Total: opt.UintWith(10)},
+			ReadChar:   Counter{Total: opt.UintWith(10)},
+		},
+		SampleTime: s0.SampleTime.Add(2 * time.Second),
+	}
+
+	got := GetProcIORate(s0, s1)
+	assert.Equal(t, float64(10000), got.IO.ReadBytes.Rate.ValueOr(0))
+	// counter reset
+	assert.False(t, got.IO.WriteBytes.Rate.Exists())
+	// no previous sample
+	assert.False(t, got.IO.ReadChar.Rate.Exists())
+}
+
+func TestSelfIO(t *testing.T) {
+	stat, err := initTestResolver()
+	require.NoError(t, err)
+	stat.EnableIO = true
+
+	_, err = stat.GetSelf()
+	require.NoError(t, err)
+	time.Sleep(time.Millisecond * 5)
+	self, err := stat.GetSelf()
+	require.NoError(t, err)
+
+	assert.True(t, self.IO.ReadChar.Total.Exists())
+	assert.True(t, self.IO.ReadChar.Rate.Exists())
+}
@@ -0,0 +1,83 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build darwin || freebsd || linux || windows || aix || netbsd || openbsd
+
+package process
+
+import (
+	"sort"
+)
+
+// LibraryIndex maps shared libraries to the processes that have them loaded.
+// It is not updated when the underlying ProcsMap changes; build a new one for each snapshot.
+type LibraryIndex struct {
+	users map[string][]LibraryUser
+}
+
+// LibraryUser is a process that has a shared library loaded
+type LibraryUser struct {
+	Pid int
+	// Deleted is set if the process still uses a copy of the library that was removed or replaced
+	Deleted bool
+}
+
+// NewLibraryIndex builds a library index from the shared libraries of the processes in the given ProcsMap.
+func NewLibraryIndex(procs ProcsMap) *LibraryIndex {
+	index := &LibraryIndex{users: map[string][]LibraryUser{}}
+	for pid, proc := range procs {
+		for _, lib := range proc.Libraries {
+			index.users[lib.Path] = append(index.users[lib.Path], LibraryUser{Pid: pid, Deleted: lib.Deleted})
+		}
+	}
+	for _, users := range index.users {
+		sort.Slice(users, func(i, j int) bool { return users[i].Pid < users[j].Pid })
+	}
+	return index
+}
+
+// LibraryIndex returns a library index built from the processes tracked during the last fetch.
+// It is only populated when EnableLibraries is set.
+func (procStats *Stats) LibraryIndex() *LibraryIndex {
+	return NewLibraryIndex(procStats.ProcsMap.Snapshot())
+}
+
+// Paths returns the paths of all indexed libraries.
+func (li *LibraryIndex) Paths() []string {
+	paths := make([]string, 0, len(li.users))
+	for path := range li.users {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Users returns the processes that have the library at path loaded, sorted by PID.
+func (li *LibraryIndex) Users(path string) []LibraryUser {
+	return append([]LibraryUser(nil), li.users[path]...)
+}
+
+// Stale returns the PIDs of the processes that still use a deleted or replaced copy of the library at path.
+func (li *LibraryIndex) Stale(path string) []int {
+	var pids []int
+	for _, user := range li.users[path] {
+		if user.Deleted {
+			pids = append(pids, user.Pid)
+		}
+	}
+	return pids
+}
@@ -0,0 +1,102 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package process
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/elastic-agent-libs/opt"
+	"github.com/elastic/elastic-agent-system-metrics/metric/system/resolve"
+)
+
+// getLibraries fetches the shared objects mapped by a process from /proc/[PID]/maps
+func getLibraries(hostfs resolve.Resolver, pid int) ([]SharedLibrary, error) {
+	path := hostfs.Join("proc", strconv.Itoa(pid), "maps")
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening file %s: %w", path, err)
+	}
+	defer f.Close()
+
+	sizes := map[string]uint64{}
+	deleted := map[string]bool{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		start, end, file, err := parseMapsLine(scanner.Text())
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s: %w", path, err)
+		}
+		file, isDeleted := strings.CutSuffix(file, " (deleted)")
+		if !isSharedObject(file) {
+			continue
+		}
+		sizes[file] += end - start
+		deleted[file] = deleted[file] || isDeleted
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", path, err)
+	}
+
+	libs := make([]SharedLibrary, 0, len(sizes))
+	for file, size := range sizes {
+		libs = append(libs, SharedLibrary{Path: file, Deleted: deleted[file], Size: opt.UintWith(size)})
+	}
+	sort.Slice(libs, func(i, j int) bool { return libs[i].Path < libs[j].Path })
+	return libs, nil
+}
+
+// parseMapsLine returns the address range and the pathname of a line of /proc/[PID]/maps, see proc(5).
+// The pathname is empty for anonymous mappings.
+func parseMapsLine(line string) (uint64, uint64, string, error) {
+	addr, rest, _ := strings.Cut(line, " ")
+	startStr, endStr, ok := strings.Cut(addr, "-")
+	if !ok {
+		return 0, 0, "", fmt.Errorf("invalid address range '%s'", addr)
+	}
+	start, err := strconv.ParseUint(startStr, 16, 64)
+	if err != nil {
+		return 0, 0, "", fmt.Errorf("error parsing address '%s': %w", startStr, err)
+	}
+	end, err := strconv.ParseUint(endStr, 16, 64)
+	if err != nil {
+		return 0, 0, "", fmt.Errorf("error parsing address '%s': %w", endStr, err)
+	}
+
+	// skip perms, offset, dev and inode; the pathname may contain spaces
+	for i := 0; i < 4; i++ {
+		_, rest, _ = strings.Cut(strings.TrimLeft(rest, " "), " ")
+	}
+	return start, end, strings.TrimLeft(rest, " "), nil
+}
+
+// isSharedObject returns true for paths such as libc.so.6 and ld-linux-x86-64.so.2
+func isSharedObject(path string) bool {
+	if !strings.HasPrefix(path, "/") {
+		return false
+	}
+	base := filepath.Base(path)
+	return strings.HasSuffix(base, ".so") || strings.Contains(base, ".so.")
+}
@@ -0,0 +1,55 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package process
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-libs/opt"
+	"github.com/elastic/elastic-agent-system-metrics/metric/system/resolve"
+)
+
+func TestGetLibraries(t *testing.T) {
+	got, err := getLibraries(resolve.NewTestResolver("testdata"), 42)
+	require.NoError(t, err)
+	assert.Equal(t, []SharedLibrary{
+		{Path: "/usr/lib/x86_64-linux-gnu/ld-linux-x86-64.so.2", Size: opt.UintWith(0x31000)},
+		{Path: "/usr/lib/x86_64-linux-gnu/libc.so.6", Size: opt.UintWith(0x1d5000)},
+		{Path: "/usr/lib/x86_64-linux-gnu/libssl.so.3", Deleted: true, Size: opt.UintWith(0x64000)},
+	}, got)
+}
+
+func TestParseMapsLine(t *testing.T) {
+	start, end, path, err := parseMapsLine("7f2a20e00000-7f2a20e10000 r--s 00000000 00:01 2049                       /dev/shm/agent cache")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0x7f2a20e00000), start)
+	assert.Equal(t, uint64(0x7f2a20e10000), end)
+	assert.Equal(t, "/dev/shm/agent cache", path)
+
+	_, _, path, err = parseMapsLine("7f2a1c000000-7f2a1c021000 rw-p 00000000 00:00 0 ")
+	require.NoError(t, err)
+	assert.Empty(t, path)
+
+	_, _, _, err = parseMapsLine("garbage")
+	assert.Error(t, err)
+}
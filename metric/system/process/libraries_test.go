@@ -0,0 +1,44 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build darwin || freebsd || linux || windows
+
+package process
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLibraryIndex(t *testing.T) {
+	libc := "/usr/lib/libc.so.6"
+	ssl := "/usr/lib/libssl.so.3"
+	procs := ProcsMap{
+		20: {Libraries: []SharedLibrary{{Path: libc}, {Path: ssl, Deleted: true}}},
+		10: {Libraries: []SharedLibrary{{Path: libc}, {Path: ssl}}},
+		30: {},
+	}
+	index := NewLibraryIndex(procs)
+
+	assert.Equal(t, []string{libc, ssl}, index.Paths())
+	assert.Equal(t, []LibraryUser{{Pid: 10}, {Pid: 20}}, index.Users(libc))
+	assert.Equal(t, []LibraryUser{{Pid: 10}, {Pid: 20, Deleted: true}}, index.Users(ssl))
+	assert.Equal(t, []int{20}, index.Stale(ssl))
+	assert.Empty(t, index.Stale(libc))
+	assert.Empty(t, index.Users("/usr/lib/libz.so.1"))
+}
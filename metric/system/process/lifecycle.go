@@ -0,0 +1,106 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build darwin || freebsd || linux || windows || aix || netbsd || openbsd
+
+package process
+
+import (
+	"sort"
+	"time"
+)
+
+// LifecycleEventType is the type of a process lifecycle event
+type LifecycleEventType string
+
+const (
+	// ProcessStarted is reported for a process that was not part of the previous snapshot
+	ProcessStarted LifecycleEventType = "started"
+	// ProcessExited is reported for a process that is no longer part of the current snapshot
+	ProcessExited LifecycleEventType = "exited"
+)
+
+// LifecycleEvent describes a process that appeared or disappeared between two snapshots.
+type LifecycleEvent struct {
+	Type LifecycleEventType
+	Pid  int
+	// Process is the current state of a started process, or the last known state of an exited process.
+	Process ProcState
+	// Lifetime is the time between the start of an exited process and the last sample it was seen in.
+	Lifetime time.Duration
+	// PidReused is set when the PID was taken over by another process between the two snapshots.
+	// In this case both an exited and a started event are reported for the PID.
+	PidReused bool
+}
+
+// DiffProcsMaps compares two successive snapshots and returns started and exited events,
+// sorted by PID. A PID that exists in both snapshots with a different start time is reported as reused.
+func DiffProcsMaps(prev, cur ProcsMap) []LifecycleEvent {
+	var events []LifecycleEvent
+
+	for pid, last := range prev {
+		proc, ok := cur[pid]
+		if ok && isSameProcess(last, proc) {
+			continue
+		}
+		events = append(events, LifecycleEvent{
+			Type:      ProcessExited,
+			Pid:       pid,
+			Process:   last,
+			Lifetime:  getLifetime(last),
+			PidReused: ok,
+		})
+	}
+
+	for pid, proc := range cur {
+		last, ok := prev[pid]
+		if ok && isSameProcess(last, proc) {
+			continue
+		}
+		events = append(events, LifecycleEvent{
+			Type:      ProcessStarted,
+			Pid:       pid,
+			Process:   proc,
+			PidReused: ok,
+		})
+	}
+
+	// exited events come first for a reused PID
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].Pid == events[j].Pid {
+			return events[i].Type == ProcessExited
+		}
+		return events[i].Pid < events[j].Pid
+	})
+
+	return events
+}
+
+// LifecycleEvents returns the processes that were started or exited between the last two calls to Get().
+// No events are reported after the first call to Get(), as there is nothing to compare against.
+func (procStats *Stats) LifecycleEvents() []LifecycleEvent {
+	return procStats.lifecycleEvents
+}
+
+// getLifetime returns the time between the start time of a process and the time it was last sampled
+func getLifetime(proc ProcState) time.Duration {
+	start, ok := getStartTime(proc)
+	if !ok || proc.SampleTime.IsZero() || proc.SampleTime.Before(start) {
+		return 0
+	}
+	return proc.SampleTime.Sub(start)
+}
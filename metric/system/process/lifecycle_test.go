@@ -0,0 +1,97 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build darwin || freebsd || linux || windows
+
+package process
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffProcsMaps(t *testing.T) {
+	start := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	t0 := start.Add(time.Hour)
+	t1 := t0.Add(10 * time.Second)
+
+	prev := ProcsMap{
+		1: testProc{pid: 1, start: start, sample: t0}.state(),
+		2: testProc{pid: 2, start: start.Add(time.Minute), sample: t0}.state(),
+		3: testProc{pid: 3, start: start.Add(2 * time.Minute), sample: t0}.state(),
+	}
+	cur := ProcsMap{
+		1: testProc{pid: 1, start: start, sample: t1}.state(),
+		3: testProc{pid: 3, start: t0.Add(5 * time.Second), sample: t1}.state(),
+		4: testProc{pid: 4, start: t0.Add(time.Second), sample: t1}.state(),
+	}
+
+	events := DiffProcsMaps(prev, cur)
+	require.Len(t, events, 4)
+
+	assert.Equal(t, ProcessExited, events[0].Type)
+	assert.Equal(t, 2, events[0].Pid)
+	assert.Equal(t, 59*time.Minute, events[0].Lifetime)
+	assert.Equal(t, t0, events[0].Process.SampleTime)
+	assert.False(t, events[0].PidReused)
+
+	assert.Equal(t, ProcessExited, events[1].Type)
+	assert.Equal(t, 3, events[1].Pid)
+	assert.Equal(t, 58*time.Minute, events[1].Lifetime)
+	assert.True(t, events[1].PidReused)
+
+	assert.Equal(t, ProcessStarted, events[2].Type)
+	assert.Equal(t, 3, events[2].Pid)
+	assert.Equal(t, t1, events[2].Process.SampleTime)
+	assert.Zero(t, events[2].Lifetime)
+	assert.True(t, events[2].PidReused)
+
+	assert.Equal(t, ProcessStarted, events[3].Type)
+	assert.Equal(t, 4, events[3].Pid)
+	assert.False(t, events[3].PidReused)
+
+	assert.Empty(t, DiffProcsMaps(cur, cur))
+}
+
+func TestStatsLifecycleEvents(t *testing.T) {
+	stat, err := initTestResolver()
+	require.NoError(t, err)
+
+	_, _, err = stat.Get()
+	require.NoError(t, err)
+	assert.Empty(t, stat.LifecycleEvents(), "the first fetch has nothing to compare against")
+
+	// pretend a process was seen in the previous fetch
+	snapshot := stat.ProcsMap.Snapshot()
+	snapshot[1<<30] = testProc{pid: 1 << 30, start: time.Now().Add(-time.Minute), sample: time.Now()}.state()
+	stat.ProcsMap.SetMap(snapshot)
+
+	_, _, err = stat.Get()
+	require.NoError(t, err)
+	found := false
+	for _, event := range stat.LifecycleEvents() {
+		if event.Pid == 1<<30 {
+			found = true
+			assert.Equal(t, ProcessExited, event.Type)
+			assert.NotZero(t, event.Lifetime)
+		}
+	}
+	assert.True(t, found, "exited process not reported")
+}
@@ -0,0 +1,85 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package process
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-libs/opt"
+	"github.com/elastic/elastic-agent-system-metrics/metric/system/resolve"
+)
+
+func TestGetLimits(t *testing.T) {
+	got, err := getLimits(resolve.NewTestResolver("testdata"), 42, procFiles{})
+	require.NoError(t, err)
+
+	unlimited := ResourceLimit{SoftUnlimited: true, HardUnlimited: true}
+	assert.Equal(t, unlimited, got.CPUTime)
+	assert.Equal(t, unlimited, got.AddressSpace)
+	assert.Equal(t, unlimited, got.RTTime)
+	assert.Equal(t, ResourceLimit{Soft: opt.UintWith(8388608), HardUnlimited: true}, got.Stack)
+	assert.Equal(t, ResourceLimit{Soft: opt.UintWith(0), HardUnlimited: true}, got.Core)
+	assert.Equal(t, ResourceLimit{Soft: opt.UintWith(63448), Hard: opt.UintWith(63448)}, got.Processes)
+	assert.Equal(t, ResourceLimit{Soft: opt.UintWith(1024), Hard: opt.UintWith(524288)}, got.OpenFiles)
+	assert.Equal(t, ResourceLimit{Soft: opt.UintWith(0), Hard: opt.UintWith(0)}, got.Nice)
+	assert.Equal(t, ResourceLimit{Soft: opt.UintWith(0), Hard: opt.UintWith(0)}, got.RTPriority)
+
+	_, err = parseLimits([]byte("Max open files            lots                 524288               files\n"))
+	assert.Error(t, err)
+}
+
+func TestParseLimitsUnlimited(t *testing.T) {
+	limits, err := parseLimits([]byte("Max open files            unlimited            unlimited            files\n"))
+	require.NoError(t, err)
+	assert.False(t, limits.OpenFiles.Soft.Exists())
+	assert.True(t, limits.OpenFiles.SoftUnlimited)
+}
+
+func TestProcLimitUsage(t *testing.T) {
+	state := ProcState{
+		NumThreads: opt.IntWith(26),
+		FD:         ProcFDInfo{Open: opt.UintWith(256)},
+		Memory:     ProcMemInfo{Size: opt.UintWith(2675654656)},
+		Limits: ProcResourceLimits{
+			Processes:    ResourceLimit{Soft: opt.UintWith(100), Hard: opt.UintWith(200)},
+			OpenFiles:    ResourceLimit{Soft: opt.UintWith(1024), Hard: opt.UintWith(524288)},
+			AddressSpace: ResourceLimit{SoftUnlimited: true, HardUnlimited: true},
+		},
+	}
+
+	state = GetProcLimitUsage(state)
+	assert.Equal(t, 0.26, state.Limits.Processes.Pct.ValueOr(0))
+	assert.Equal(t, 0.25, state.Limits.OpenFiles.Pct.ValueOr(0))
+	assert.False(t, state.Limits.AddressSpace.Pct.Exists())
+}
+
+func TestGetLimitsParsed(t *testing.T) {
+	// the limits parsed by FillPidMetrics are used, there is nothing to read for this pid
+	parsed := ProcResourceLimits{OpenFiles: ResourceLimit{Soft: opt.UintWith(1024), Hard: opt.UintWith(4096)}}
+	got, err := getLimits(resolve.NewTestResolver(t.TempDir()), 42, procFiles{limits: &parsed})
+	require.NoError(t, err)
+	assert.Equal(t, parsed, got)
+
+	assert.False(t, got.IsZero())
+	assert.True(t, ProcResourceLimits{}.IsZero())
+}
@@ -0,0 +1,125 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package process
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/elastic-agent-libs/opt"
+	"github.com/elastic/elastic-agent-system-metrics/metric/system/resolve"
+)
+
+// getMemDetail fills out the PSS, USS, and swap metrics from /proc/[PID]/smaps_rollup,
+// falling back to /proc/[PID]/smaps on kernels older than 4.14.
+func getMemDetail(hostfs resolve.Resolver, pid int, state ProcMemInfo) (ProcMemInfo, error) {
+	path := hostfs.Join("proc", strconv.Itoa(pid), "smaps_rollup")
+	f, err := os.Open(path)
+	perMapping := false
+	if errors.Is(err, os.ErrNotExist) {
+		path = hostfs.Join("proc", strconv.Itoa(pid), "smaps")
+		f, err = os.Open(path)
+		perMapping = true
+	}
+	if err != nil {
+		return state, fmt.Errorf("error opening file %s: %w", path, err)
+	}
+	defer f.Close()
+
+	smaps, err := parseSmaps(bufio.NewScanner(f), perMapping)
+	if err != nil {
+		return state, fmt.Errorf("error parsing %s: %w", path, err)
+	}
+
+	state.Pss = optKB(smaps, "Pss")
+	state.Anonymous = optKB(smaps, "Anonymous")
+	state.Swap = optKB(smaps, "Swap")
+	// Pss_File and Pss_Shmem are only reported by smaps_rollup on 5.x kernels,
+	// and are summed up from the mapping headers by parseSmaps when falling back to smaps
+	state.File = optKB(smaps, "Pss_File")
+	state.Shmem = optKB(smaps, "Pss_Shmem")
+	if _, ok := smaps["Private_Clean"]; ok {
+		state.Uss = opt.UintWith((smaps["Private_Clean"] + smaps["Private_Dirty"] + smaps["Private_Hugetlb"]) * 1024)
+	}
+
+	return state, nil
+}
+
+// parseSmaps sums up every "Key: value kB" line in a smaps or smaps_rollup file.
+// smaps_rollup only has a single entry, smaps has one entry for each mapping.
+// For smaps, perMapping also sums up the Pss of file-backed and shared memory mappings
+// as Pss_File and Pss_Shmem, which only smaps_rollup reports.
+func parseSmaps(sc *bufio.Scanner, perMapping bool) (map[string]uint64, error) {
+	smaps := map[string]uint64{}
+	if perMapping {
+		smaps["Pss_File"], smaps["Pss_Shmem"] = 0, 0
+	}
+	mappingKey := ""
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if perMapping && len(fields) >= 5 && strings.Contains(fields[0], "-") && !strings.HasSuffix(fields[0], ":") {
+			mappingKey = smapsMappingKey(fields)
+			continue
+		}
+		if len(fields) != 3 || fields[2] != "kB" || !strings.HasSuffix(fields[0], ":") {
+			continue
+		}
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing value for %s: %w", fields[0], err)
+		}
+		key := strings.TrimSuffix(fields[0], ":")
+		smaps[key] += value
+		if key == "Pss" && mappingKey != "" {
+			smaps[mappingKey] += value
+		}
+	}
+
+	return smaps, sc.Err()
+}
+
+// smapsMappingKey returns the key the Pss of a mapping is summed up in, from its smaps header line,
+// "address perms offset dev inode [pathname]". Mappings of files in /dev/shm, SysV shared memory,
+// memfds and shared anonymous memory are counted as shared memory, other mappings with an inode as files.
+// Other tmpfs files can't be told apart from the header, and are counted as files.
+func smapsMappingKey(header []string) string {
+	pathname := strings.Join(header[5:], " ")
+	switch {
+	case strings.HasPrefix(pathname, "/dev/shm/"), strings.HasPrefix(pathname, "/SYSV"),
+		strings.HasPrefix(pathname, "/memfd:"), strings.HasPrefix(pathname, "/dev/zero"):
+		return "Pss_Shmem"
+	case header[4] != "0":
+		return "Pss_File"
+	}
+	return ""
+}
+
+// optKB returns the given key as a byte value, or None if it does not exist.
+func optKB(values map[string]uint64, key string) opt.Uint {
+	value, ok := values[key]
+	if !ok {
+		return opt.NewUintNone()
+	}
+	return opt.UintWith(value * 1024)
+}
@@ -0,0 +1,76 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package process
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-libs/opt"
+	"github.com/elastic/elastic-agent-system-metrics/metric/system/resolve"
+)
+
+func TestGetMemDetailRollup(t *testing.T) {
+	got, err := getMemDetail(resolve.NewTestResolver("testdata"), 42, ProcMemInfo{})
+	require.NoError(t, err)
+
+	want := ProcMemInfo{
+		Pss:       opt.UintWith(45312 * 1024),
+		Uss:       opt.UintWith((13380 + 30120) * 1024),
+		Anonymous: opt.UintWith(30104 * 1024),
+		File:      opt.UintWith(15184 * 1024),
+		Shmem:     opt.UintWith(24 * 1024),
+		Swap:      opt.UintWith(2048 * 1024),
+	}
+	assert.Equal(t, want, got)
+}
+
+func TestGetMemDetailSmapsFallback(t *testing.T) {
+	// No smaps_rollup in the test data for this pid, so this sums up all the mappings in smaps
+	got, err := getMemDetail(resolve.NewTestResolver("testdata"), 43, ProcMemInfo{Size: opt.UintWith(1)})
+	require.NoError(t, err)
+
+	want := ProcMemInfo{
+		Size:      opt.UintWith(1),
+		Pss:       opt.UintWith(270 * 1024),
+		Uss:       opt.UintWith(100 * 1024),
+		Anonymous: opt.UintWith(100 * 1024),
+		File:      opt.UintWith(150 * 1024),
+		Shmem:     opt.UintWith(20 * 1024),
+		Swap:      opt.UintWith(12 * 1024),
+	}
+	assert.Equal(t, want, got)
+}
+
+func TestSelfMemDetail(t *testing.T) {
+	stat, err := initTestResolver()
+	require.NoError(t, err)
+	stat.EnableMemoryDetail = true
+
+	self, err := stat.GetSelf()
+	require.NoError(t, err)
+	assert.Equal(t, os.Getpid(), self.Pid.ValueOr(0))
+	assert.NotZero(t, self.Memory.Pss.ValueOr(0))
+	assert.NotZero(t, self.Memory.Uss.ValueOr(0))
+	assert.LessOrEqual(t, self.Memory.Uss.ValueOr(0), self.Memory.Rss.Bytes.ValueOr(0))
+}
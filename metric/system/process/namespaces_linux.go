@@ -0,0 +1,149 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package process
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/elastic-agent-libs/opt"
+	"github.com/elastic/elastic-agent-system-metrics/metric/system/resolve"
+)
+
+// namespaceTypes are the entries of /proc/[PID]/ns that are reported
+var namespaceTypes = []string{"pid", "net", "mnt", "uts", "ipc", "user", "cgroup", "time"}
+
+// getNamespaces fetches the namespaces of a process from /proc/[PID]/ns and /proc/[PID]/status.
+// host contains the namespace IDs of PID 1, used to flag namespaces that differ from the host.
+// If host is nil, DiffersFromHost is left unset.
+// The ns links require ptrace access to the process, while the status file is world-readable,
+// so the NSpid and NStgid chains are returned even if the links can't be read.
+func getNamespaces(hostfs resolve.Resolver, pid int, host map[string]uint64) (ProcNamespaces, error) {
+	state := ProcNamespaces{}
+
+	ids, nsErr := getNamespaceIDs(hostfs, pid)
+	for name, id := range ids {
+		ns := namespaceField(&state, name)
+		ns.ID = opt.UintWith(id)
+		if hostID, ok := host[name]; ok {
+			differs := hostID != id
+			ns.DiffersFromHost = &differs
+		}
+	}
+
+	status, err := getProcStatus(hostfs, pid)
+	if err != nil {
+		return state, fmt.Errorf("error fetching status for pid %d: %w", pid, err)
+	}
+	state.NSpid, err = parseNSList(status["NSpid"])
+	if err != nil {
+		return state, fmt.Errorf("error parsing NSpid for pid %d: %w", pid, err)
+	}
+	state.NStgid, err = parseNSList(status["NStgid"])
+	if err != nil {
+		return state, fmt.Errorf("error parsing NStgid for pid %d: %w", pid, err)
+	}
+
+	return state, nsErr
+}
+
+// isNamespaceInit returns true if the process is PID 1 of a nested PID namespace, such as the init process of a container
+func isNamespaceInit(hostfs resolve.Resolver, pid int) (bool, error) {
+	status, err := getProcStatus(hostfs, pid)
+	if err != nil {
+		return false, fmt.Errorf("error fetching status for pid %d: %w", pid, err)
+	}
+	nspid, err := parseNSList(status["NSpid"])
+	if err != nil {
+		return false, fmt.Errorf("error parsing NSpid for pid %d: %w", pid, err)
+	}
+	return len(nspid) > 1 && nspid[len(nspid)-1] == 1, nil
+}
+
+// getNamespaceIDs reads the namespace inode numbers of a process.
+// Namespace types that are not supported by the running kernel are skipped.
+func getNamespaceIDs(hostfs resolve.Resolver, pid int) (map[string]uint64, error) {
+	ids := make(map[string]uint64, len(namespaceTypes))
+	for _, name := range namespaceTypes {
+		path := hostfs.Join("proc", strconv.Itoa(pid), "ns", name)
+		link, err := os.Readlink(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return ids, fmt.Errorf("error reading namespace link %s: %w", path, err)
+		}
+		id, err := parseNamespaceLink(link)
+		if err != nil {
+			return ids, fmt.Errorf("error parsing namespace link %s: %w", path, err)
+		}
+		ids[name] = id
+	}
+	return ids, nil
+}
+
+// parseNamespaceLink parses the target of a namespace link, in the form "net:[4026531840]"
+func parseNamespaceLink(link string) (uint64, error) {
+	start := strings.Index(link, ":[")
+	if start < 0 || !strings.HasSuffix(link, "]") {
+		return 0, fmt.Errorf("unexpected namespace link '%s'", link)
+	}
+	return strconv.ParseUint(link[start+2:len(link)-1], 10, 64)
+}
+
+// parseNSList parses a tab-separated list of IDs from the NSpid and NStgid fields of /proc/[PID]/status
+func parseNSList(raw string) ([]int, error) {
+	fields := strings.Fields(raw)
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	ids := make([]int, 0, len(fields))
+	for _, field := range fields {
+		id, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func namespaceField(state *ProcNamespaces, name string) *Namespace {
+	switch name {
+	case "pid":
+		return &state.PID
+	case "net":
+		return &state.Net
+	case "mnt":
+		return &state.Mnt
+	case "uts":
+		return &state.UTS
+	case "ipc":
+		return &state.IPC
+	case "user":
+		return &state.User
+	case "cgroup":
+		return &state.Cgroup
+	default:
+		return &state.Time
+	}
+}
@@ -0,0 +1,88 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package process
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-system-metrics/metric/system/resolve"
+)
+
+func TestParseNamespaceLink(t *testing.T) {
+	id, err := parseNamespaceLink("net:[4026531840]")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4026531840), id)
+
+	_, err = parseNamespaceLink("net:4026531840")
+	assert.Error(t, err)
+}
+
+func TestGetNamespacesNSpid(t *testing.T) {
+	// the testdata has no ns links, as symlinks can't be part of a go module
+	got, err := getNamespaces(resolve.NewTestResolver("testdata"), 42, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, []int{42, 7}, got.NSpid)
+	assert.Equal(t, []int{42, 7}, got.NStgid)
+	assert.False(t, got.PID.ID.Exists())
+	assert.Nil(t, got.PID.DiffersFromHost)
+}
+
+func TestGetNamespacesUnreadableLinks(t *testing.T) {
+	root := t.TempDir()
+	procDir := filepath.Join(root, "proc", "42")
+	require.NoError(t, os.MkdirAll(filepath.Join(procDir, "ns"), 0o755))
+	status, err := os.ReadFile(filepath.Join("testdata", "proc", "42", "status"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(procDir, "status"), status, 0o644))
+	// not a symlink, so reading the link fails like it does without ptrace access
+	require.NoError(t, os.WriteFile(filepath.Join(procDir, "ns", "pid"), nil, 0o644))
+
+	got, err := getNamespaces(resolve.NewTestResolver(root), 42, nil)
+	assert.Error(t, err)
+	assert.Equal(t, []int{42, 7}, got.NSpid)
+	assert.Equal(t, []int{42, 7}, got.NStgid)
+}
+
+func TestGetNamespacesSelf(t *testing.T) {
+	hostfs := resolve.NewTestResolver("/")
+	self, err := getNamespaceIDs(hostfs, os.Getpid())
+	require.NoError(t, err)
+	require.Contains(t, self, "pid")
+
+	got, err := getNamespaces(hostfs, os.Getpid(), self)
+	require.NoError(t, err)
+	assert.Equal(t, self["net"], got.Net.ID.ValueOr(0))
+	require.NotNil(t, got.Net.DiffersFromHost)
+	assert.False(t, *got.Net.DiffersFromHost)
+	assert.Equal(t, os.Getpid(), got.NSpid[0])
+
+	other := map[string]uint64{"net": self["net"] + 1}
+	got, err = getNamespaces(hostfs, os.Getpid(), other)
+	require.NoError(t, err)
+	require.NotNil(t, got.Net.DiffersFromHost)
+	assert.True(t, *got.Net.DiffersFromHost)
+	assert.Nil(t, got.PID.DiffersFromHost, "not part of the host namespaces")
+}
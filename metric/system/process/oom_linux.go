@@ -0,0 +1,60 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package process
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/elastic-agent-libs/opt"
+	"github.com/elastic/elastic-agent-system-metrics/metric/system/resolve"
+)
+
+// getOOMScore fetches the OOM killer score from /proc/[PID]/oom_score and /proc/[PID]/oom_score_adj
+func getOOMScore(hostfs resolve.Resolver, pid int) (ProcOOMInfo, error) {
+	state := ProcOOMInfo{}
+	score, err := readIntFile(hostfs.Join("proc", strconv.Itoa(pid), "oom_score"))
+	if err != nil {
+		return state, err
+	}
+	state.Score = opt.IntWith(score)
+
+	adj, err := readIntFile(hostfs.Join("proc", strconv.Itoa(pid), "oom_score_adj"))
+	if err != nil {
+		return state, err
+	}
+	state.ScoreAdj = opt.IntWith(adj)
+
+	return state, nil
+}
+
+func readIntFile(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("error opening file %s: %w", path, err)
+	}
+	value, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("error parsing %s: %w", path, err)
+	}
+	return value, nil
+}
@@ -0,0 +1,64 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package process
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/elastic/elastic-agent-system-metrics/metric/system/resolve"
+)
+
+// getOwner fetches the real, effective and saved users and groups, and the supplementary groups,
+// of a process from /proc/[PID]/status, and resolves their names under the hostfs root.
+func getOwner(hostfs resolve.Resolver, pid int) (ProcOwner, error) {
+	state := ProcOwner{}
+	status, err := getProcStatus(hostfs, pid)
+	if err != nil {
+		return state, fmt.Errorf("error fetching status for pid %d: %w", pid, err)
+	}
+
+	// The Uid and Gid fields are real, effective, saved set and filesystem IDs
+	uids := strings.Fields(status["Uid"])
+	gids := strings.Fields(status["Gid"])
+	if len(uids) < 3 || len(gids) < 3 {
+		return state, fmt.Errorf("unexpected Uid or Gid fields '%s', '%s' for pid %d", status["Uid"], status["Gid"], pid)
+	}
+
+	db := newUserDB(hostfs)
+	user := func(uid string) OwnerID {
+		return OwnerID{ID: uid, Name: db.userName(uid)}
+	}
+	group := func(gid string) OwnerID {
+		return OwnerID{ID: gid, Name: db.groupName(gid)}
+	}
+
+	state.RealUser = user(uids[0])
+	state.User = user(uids[1])
+	state.SavedUser = user(uids[2])
+	state.RealGroup = group(gids[0])
+	state.Group = group(gids[1])
+	state.SavedGroup = group(gids[2])
+	for _, gid := range strings.Fields(status["Groups"]) {
+		state.SupplementalGroups = append(state.SupplementalGroups, group(gid))
+	}
+
+	return state, nil
+}
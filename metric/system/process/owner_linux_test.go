@@ -0,0 +1,72 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package process
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-system-metrics/metric/system/resolve"
+)
+
+func TestGetOwner(t *testing.T) {
+	got, err := getOwner(resolve.NewTestResolver("testdata"), 42)
+	require.NoError(t, err)
+
+	elastic := OwnerID{ID: "1000", Name: "elastic"}
+	assert.Equal(t, elastic, got.User)
+	assert.Equal(t, elastic, got.RealUser)
+	assert.Equal(t, elastic, got.SavedUser)
+	assert.Equal(t, elastic, got.Group)
+	assert.Equal(t, []OwnerID{
+		{ID: "4", Name: "adm"},
+		{ID: "27", Name: "sudo"},
+		{ID: "1000", Name: "elastic"},
+	}, got.SupplementalGroups)
+
+	username, err := getUser(resolve.NewTestResolver("testdata"), 42)
+	require.NoError(t, err)
+	assert.Equal(t, "elastic", username)
+}
+
+func TestUserDBReload(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(root, "etc"), 0o755))
+	passwd := filepath.Join(root, "etc", "passwd")
+	hostfs := resolve.NewTestResolver(root)
+
+	require.NoError(t, os.WriteFile(passwd, []byte("app:x:1001:1001::/:/bin/sh\n"), 0o644))
+	db := newUserDB(hostfs)
+	assert.Equal(t, "app", db.userName("1001"))
+	assert.Empty(t, db.userName("1002"), "unknown users must not be looked up outside of hostfs")
+	assert.Empty(t, db.groupName("1001"), "missing group file")
+
+	require.NoError(t, os.WriteFile(passwd, []byte("renamed:x:1001:1001::/:/bin/sh\nother:x:1002:1002::/:/bin/sh\n"), 0o644))
+	// make sure the change is visible even on filesystems with a coarse mtime resolution
+	require.NoError(t, os.Chtimes(passwd, time.Now(), time.Now().Add(time.Minute)))
+	db = newUserDB(hostfs)
+	assert.Equal(t, "renamed", db.userName("1001"))
+	assert.Equal(t, "other", db.userName("1002"))
+}
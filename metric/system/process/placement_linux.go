@@ -0,0 +1,134 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package process
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/elastic-agent-libs/opt"
+	"github.com/elastic/elastic-agent-system-metrics/metric/system/cgroup/cgcommon"
+	"github.com/elastic/elastic-agent-system-metrics/metric/system/resolve"
+)
+
+// getPlacement fetches the allowed CPUs and NUMA nodes from /proc/[PID]/status,
+// and if numa is set, the memory per NUMA node from /proc/[PID]/numa_maps.
+func getPlacement(hostfs resolve.Resolver, pid int, numa bool) (ProcPlacement, error) {
+	state := ProcPlacement{}
+	status, err := getProcStatus(hostfs, pid)
+	if err != nil {
+		return state, fmt.Errorf("error fetching status for pid %d: %w", pid, err)
+	}
+
+	state.CPUsAllowed = status["Cpus_allowed_list"]
+	if state.CPUsAllowed != "" {
+		count, err := cgcommon.CountListEntries(state.CPUsAllowed)
+		if err != nil {
+			return state, fmt.Errorf("error parsing Cpus_allowed_list for pid %d: %w", pid, err)
+		}
+		state.CPUsAllowedCount = opt.IntWith(count)
+	}
+	state.MemsAllowed = status["Mems_allowed_list"]
+	if state.MemsAllowed != "" {
+		count, err := cgcommon.CountListEntries(state.MemsAllowed)
+		if err != nil {
+			return state, fmt.Errorf("error parsing Mems_allowed_list for pid %d: %w", pid, err)
+		}
+		state.MemsAllowedCount = opt.IntWith(count)
+	}
+
+	if !numa {
+		return state, nil
+	}
+	// numa_maps only exists on kernels built with CONFIG_NUMA
+	path := hostfs.Join("proc", strconv.Itoa(pid), "numa_maps")
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	} else if err != nil {
+		return state, fmt.Errorf("error opening file %s: %w", path, err)
+	}
+	defer f.Close()
+	state.NUMANodes, err = parseNUMAMaps(f)
+	if err != nil {
+		return state, fmt.Errorf("error parsing %s: %w", path, err)
+	}
+
+	return state, nil
+}
+
+// parseNUMAMaps sums the pages of each mapping in /proc/[PID]/numa_maps per NUMA node.
+// Each line lists the pages on a node as N<node>=<pages>, and the page size as kernelpagesize_kB=<size>.
+func parseNUMAMaps(r io.Reader) ([]NUMANodeMemory, error) {
+	nodes := map[int]*NUMANodeMemory{}
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		pageSize := uint64(0)
+		pages := map[int]uint64{}
+		for _, field := range fields {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				continue
+			}
+			switch {
+			case key == "kernelpagesize_kB":
+				size, err := strconv.ParseUint(value, 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("error parsing page size '%s': %w", field, err)
+				}
+				pageSize = size * 1024
+			case len(key) > 1 && key[0] == 'N':
+				node, err := strconv.Atoi(key[1:])
+				if err != nil {
+					continue
+				}
+				count, err := strconv.ParseUint(value, 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("error parsing page count '%s': %w", field, err)
+				}
+				pages[node] = count
+			}
+		}
+		for node, count := range pages {
+			if _, ok := nodes[node]; !ok {
+				nodes[node] = &NUMANodeMemory{Node: node}
+			}
+			nodes[node].Pages += count
+			nodes[node].Bytes += count * pageSize
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	list := make([]NUMANodeMemory, 0, len(nodes))
+	for _, node := range nodes {
+		list = append(list, *node)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Node < list[j].Node })
+	return list, nil
+}
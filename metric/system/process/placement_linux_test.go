@@ -0,0 +1,58 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package process
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-libs/opt"
+	"github.com/elastic/elastic-agent-system-metrics/metric/system/resolve"
+)
+
+func TestGetPlacement(t *testing.T) {
+	got, err := getPlacement(resolve.NewTestResolver("testdata"), 42, true)
+	require.NoError(t, err)
+
+	assert.Equal(t, "0-7", got.CPUsAllowed)
+	assert.Equal(t, opt.IntWith(8), got.CPUsAllowedCount)
+	assert.Equal(t, "0", got.MemsAllowed)
+	assert.Equal(t, opt.IntWith(1), got.MemsAllowedCount)
+	assert.Equal(t, []NUMANodeMemory{
+		{Node: 0, Pages: 2179, Bytes: (2048+96+33)*4096 + 2*2048*1024},
+		{Node: 1, Pages: 17152, Bytes: (768 + 16384) * 4096},
+	}, got.NUMANodes)
+
+	got, err = getPlacement(resolve.NewTestResolver("testdata"), 42, false)
+	require.NoError(t, err)
+	assert.Empty(t, got.NUMANodes)
+}
+
+func TestNormalizeCPUByAffinityEnablesPlacement(t *testing.T) {
+	stats := Stats{
+		Procs:                  []string{".*"},
+		Hostfs:                 resolve.NewTestResolver("/"),
+		NormalizeCPUByAffinity: true,
+	}
+	require.NoError(t, stats.Init())
+	assert.True(t, stats.EnablePlacement)
+}
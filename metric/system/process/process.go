@@ -90,28 +90,19 @@ func (procStats *Stats) Get() ([]mapstr.M, []mapstr.M, error) {
 		return nil, nil, nil
 	}
 
-	// actually fetch the PIDs from the OS-specific code
-	pidMap, plist, err := procStats.FetchPids()
-
+	pidMap, plist, err := procStats.fetch()
 	if err != nil {
-		return nil, nil, fmt.Errorf("error gathering PIDs: %w", err)
+		return nil, nil, err
 	}
-	// We use this to track processes over time.
-	procStats.ProcsMap.SetMap(pidMap)
 
 	// filter the process list that will be passed down to users
 	plist = procStats.includeTopProcesses(plist)
 
-	// This is a holdover until we migrate this library to metricbeat/internal
-	// At which point we'll use the memory code there.
-	var totalPhyMem uint64
-	if procStats.host != nil {
-		memStats, err := procStats.host.Memory()
-		if err != nil {
-			procStats.logger.Warnf("Getting memory details: %v", err)
-		} else {
-			totalPhyMem = memStats.Total
-		}
+	totalPhyMem := procStats.totalMemory()
+
+	var ancestry *ancestryResolver
+	if procStats.EnableAncestry {
+		ancestry = procStats.newAncestryResolver(pidMap)
 	}
 
 	// Format the list to the MapStr type used by the outputs
@@ -122,6 +113,9 @@ func (procStats *Stats) Get() ([]mapstr.M, []mapstr.M, error) {
 		process := process
 		// Add the RSS pct memory first
 		process.Memory.Rss.Pct = GetProcMemPercentage(process, totalPhyMem)
+		if ancestry != nil {
+			process = ancestry.fill(process)
+		}
 		// Create the root event
 		root := process.FormatForRoot()
 		rootMap := mapstr.M{}
@@ -139,8 +133,59 @@ func (procStats *Stats) Get() ([]mapstr.M, []mapstr.M, error) {
 	return procs, rootEvents, nil
 }
 
+// fetch runs a single scan of the configured processes, and updates the state
+// used to calculate rates and lifecycle events between scans.
+func (procStats *Stats) fetch() (ProcsMap, []ProcState, error) {
+	// socket tables are only valid for a single scan
+	if procStats.EnableSockets {
+		procStats.sockets.reset()
+	}
+
+	// cgroup limits are shared by most processes, so they are only read once per scan
+	if procStats.EnableCgroups {
+		defer procStats.cgroups.CacheLimits()()
+	}
+
+	// actually fetch the PIDs from the OS-specific code
+	pidMap, plist, err := procStats.FetchPids()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error gathering PIDs: %w", err)
+	}
+	// drop the hashes of executables that are no longer running
+	if procStats.EnableExeHash {
+		procStats.exeHashes.expire()
+	}
+	// We use this to track processes over time.
+	prevMap, ok := procStats.ProcsMap.SwapMap(pidMap)
+	procStats.lifecycleEvents = nil
+	if ok {
+		procStats.lifecycleEvents = DiffProcsMaps(prevMap, pidMap)
+	}
+
+	return pidMap, plist, nil
+}
+
+// totalMemory returns the total physical memory of the host, or 0 if it is not known.
+// This is a holdover until we migrate this library to metricbeat/internal
+// At which point we'll use the memory code there.
+func (procStats *Stats) totalMemory() uint64 {
+	if procStats.host == nil {
+		return 0
+	}
+	memStats, err := procStats.host.Memory()
+	if err != nil {
+		procStats.logger.Warnf("Getting memory details: %v", err)
+		return 0
+	}
+	return memStats.Total
+}
+
 // GetOne fetches process data for a given PID if its name matches the regexes provided from the host.
 func (procStats *Stats) GetOne(pid int) (mapstr.M, error) {
+	if procStats.EnableSockets {
+		procStats.sockets.reset()
+	}
+
 	pidStat, _, err := procStats.pidFill(pid, false)
 	if err != nil {
 		return nil, fmt.Errorf("error fetching PID %d: %w", pid, err)
@@ -154,6 +199,9 @@ func (procStats *Stats) GetOne(pid int) (mapstr.M, error) {
 // GetSelf gets process info for the beat itself
 func (procStats *Stats) GetSelf() (ProcState, error) {
 	self := os.Getpid()
+	if procStats.EnableSockets {
+		procStats.sockets.reset()
+	}
 
 	pidStat, _, err := procStats.pidFill(self, false)
 	if err != nil {
@@ -232,6 +280,9 @@ func (procStats *Stats) pidFill(pid int, filter bool) (ProcState, bool, error) {
 		if !procStats.matchProcess(status.Name) {
 			return status, false, nil
 		}
+		if procStats.ExcludeKernelThreads && status.KernelThread {
+			return status, false, nil
+		}
 	}
 
 	// If we've passed the filter, continue to fill out the rest of the metrics
@@ -240,15 +291,119 @@ func (procStats *Stats) pidFill(pid int, filter bool) (ProcState, bool, error) {
 		return status, true, fmt.Errorf("FillPidMetrics: %w", err)
 	}
 
+	if procStats.EnableMemoryDetail {
+		status.Memory, err = getMemDetail(procStats.Hostfs, pid, status.Memory)
+		procStats.softErr("fetching detailed memory metrics", pid, err)
+	}
+
+	if procStats.EnableIO {
+		status.IO, err = getIOData(procStats.Hostfs, pid)
+		procStats.accessErr("fetching I/O metrics", pid, err)
+	}
+
+	if procStats.EnableSchedStats {
+		status, err = fillSchedStats(procStats.Hostfs, pid, status)
+		procStats.softErr("fetching scheduler metrics", pid, err)
+	}
+
+	if procStats.EnableScheduling {
+		status, err = fillScheduling(procStats.Hostfs, pid, status)
+		procStats.softErr("fetching scheduling policy", pid, err)
+	}
+
+	if procStats.EnableLimits {
+		status.Limits, err = getLimits(procStats.Hostfs, pid, status.files)
+		procStats.softErr("fetching resource limits", pid, err)
+		status = GetProcLimitUsage(status)
+	}
+	// the files read by FillPidMetrics are not needed anymore
+	status.files = procFiles{}
+
+	if procStats.EnableFDDetail {
+		status.FD.Types, status.FD.TopPaths, err = getFDDetail(procStats.Hostfs, pid, procStats.FDTopPaths)
+		procStats.accessErr("classifying file descriptors", pid, err)
+	}
+
+	if procStats.EnableSockets {
+		status.Socket, err = procStats.sockets.getSockets(procStats.Hostfs, pid)
+		procStats.accessErr("fetching sockets", pid, err)
+	}
+
+	if procStats.EnableNamespaces {
+		status.Namespaces, err = getNamespaces(procStats.Hostfs, pid, procStats.hostNS)
+		procStats.accessErr("fetching namespaces", pid, err)
+	}
+
+	if procStats.EnableSecurity {
+		status.Security, err = getSecurity(procStats.Hostfs, pid)
+		procStats.accessErr("fetching security context", pid, err)
+	}
+
+	if procStats.EnableUserDetail {
+		status.Owner, err = getOwner(procStats.Hostfs, pid)
+		procStats.softErr("fetching users and groups", pid, err)
+	}
+
+	if procStats.EnablePlacement {
+		status.Placement, err = getPlacement(procStats.Hostfs, pid, procStats.EnableNUMAMemory)
+		procStats.accessErr("fetching placement", pid, err)
+	}
+
+	if procStats.EnableOOMScore {
+		status.OOM, err = getOOMScore(procStats.Hostfs, pid)
+		procStats.softErr("fetching OOM score", pid, err)
+	}
+
+	if procStats.EnableExeCheck {
+		status.ExeState, err = getExeState(procStats.Hostfs, pid)
+		procStats.accessErr("checking executable", pid, err)
+	}
+
+	if procStats.EnableExeHash {
+		status.Hash, status.BuildID, err = procStats.exeHashes.getExeHash(procStats.Hostfs, pid)
+		procStats.accessErr("hashing executable", pid, err)
+	}
+
+	if procStats.EnableLibraries {
+		status.Libraries, err = getLibraries(procStats.Hostfs, pid)
+		procStats.accessErr("fetching shared libraries", pid, err)
+	}
+
 	if status.CPU.Total.Ticks.Exists() {
 		status.CPU.Total.Value = opt.FloatWith(metric.Round(float64(status.CPU.Total.Ticks.ValueOr(0))))
 	}
 
 	// postprocess with cgroups and percentages
-	last, ok := procStats.ProcsMap.GetPid(status.Pid.ValueOr(0))
+	last, ok := procStats.ProcsMap.GetProcess(status)
 	status.SampleTime = time.Now()
 	if ok {
-		status = GetProcCPUPercentage(last, status)
+		if procStats.NormalizeCPUByAffinity {
+			status = GetProcCPUPercentageByAffinity(last, status)
+		} else {
+			status = GetProcCPUPercentage(last, status)
+		}
+		status = GetProcIORate(last, status)
+		status = GetProcSchedRate(last, status)
+	}
+
+	if procStats.StuckThreshold > 0 && isStuckState(status.State) {
+		samples := 1
+		if ok {
+			samples += last.Wait.StuckSamples.ValueOr(0)
+		}
+		status.Wait.StuckSamples = opt.IntWith(samples)
+		if samples >= procStats.StuckThreshold {
+			status.Wait.Channel, status.Wait.Stack, err = getWaitInfo(procStats.Hostfs, pid)
+			procStats.softErr("fetching wait channel", pid, err)
+		}
+	}
+
+	if procStats.EnableThreads {
+		status.Threads, err = getThreads(procStats.Hostfs, pid)
+		procStats.softErr("fetching thread metrics", pid, err)
+		if ok {
+			status = GetThreadCPUPercentage(last, status)
+		}
 	}
 
 	if procStats.EnableCgroups {
@@ -257,6 +412,8 @@ func (procStats *Stats) pidFill(pid int, filter bool) (ProcState, bool, error) {
 			return status, true, fmt.Errorf("cgroups.GetStatsForPid: %w", err)
 		}
 		status.Cgroup = cgStats
+		memLimit, cpuLimit := getCgroupLimits(status)
+		status = GetProcCgroupPercentage(status, memLimit, cpuLimit)
 		if ok {
 			status.Cgroup.FillPercentages(last.Cgroup, status.SampleTime, last.SampleTime)
 		}
@@ -293,9 +450,42 @@ func (procStats *Stats) pidFill(pid int, filter bool) (ProcState, bool, error) {
 	return status, true, nil
 }
 
-// cacheCmdLine fills out Env and arg metrics from any stored previous metrics for the pid
+// cgroupLimits is implemented by the cgroup stats of all versions
+type cgroupLimits interface {
+	MemoryLimit() (uint64, bool)
+	CPULimit() (float64, bool)
+}
+
+// getCgroupLimits returns the memory limit in bytes, and the number of CPUs, that the cgroup of a process allows.
+// Zero values mean that no limit is set.
+func getCgroupLimits(status ProcState) (uint64, float64) {
+	limits, ok := status.Cgroup.(cgroupLimits)
+	if !ok {
+		return 0, 0
+	}
+	memLimit, _ := limits.MemoryLimit()
+	cpuLimit, _ := limits.CPULimit()
+	return memLimit, cpuLimit
+}
+
+// softErr logs the error of an optional metric, without failing the whole process.
+func (procStats *Stats) softErr(what string, pid int, err error) {
+	if err != nil {
+		procStats.logger.Debugf("error %s for pid %d: %s", what, pid, err)
+	}
+}
+
+// accessErr logs the error of an optional metric that is read from a file only readable by the owner
+// of the process or with ptrace access, so permission errors are expected and are not logged.
+func (procStats *Stats) accessErr(what string, pid int, err error) {
+	if err != nil && !errors.Is(err, os.ErrPermission) {
+		procStats.logger.Debugf("error %s for pid %d: %s", what, pid, err)
+	}
+}
+
+// cacheCmdLine fills out Env and arg metrics from any stored previous metrics for the process
 func (procStats *Stats) cacheCmdLine(in ProcState) ProcState {
-	if previousProc, ok := procStats.ProcsMap.GetPid(in.Pid.ValueOr(0)); ok {
+	if previousProc, ok := procStats.ProcsMap.GetProcess(in); ok {
 		if procStats.CacheCmdLine {
 			in.Args = previousProc.Args
 			in.Cmdline = previousProc.Cmdline
@@ -314,6 +504,15 @@ func (procStats *Stats) getProcessEvent(process *ProcState) (mapstr.M, error) {
 		process.CPU.User.Ticks = opt.NewUintNone()
 		process.CPU.System.Ticks = opt.NewUintNone()
 		process.CPU.Total.Ticks = opt.NewUintNone()
+		// copy the threads so we don't remove the ticks from the values tracked in ProcsMap
+		threads := make([]ThreadState, len(process.Threads))
+		for i, thread := range process.Threads {
+			thread.CPU.User.Ticks = opt.NewUintNone()
+			thread.CPU.System.Ticks = opt.NewUintNone()
+			thread.CPU.Total.Ticks = opt.NewUintNone()
+			threads[i] = thread
+		}
+		process.Threads = threads
 	}
 
 	proc := mapstr.M{}
@@ -339,7 +538,7 @@ func (procStats *Stats) matchProcess(name string) bool {
 // includeTopProcesses filters down the metrics based on top CPU or top Memory settings
 func (procStats *Stats) includeTopProcesses(processes []ProcState) []ProcState {
 	if !procStats.IncludeTop.Enabled ||
-		(procStats.IncludeTop.ByCPU == 0 && procStats.IncludeTop.ByMemory == 0) {
+		(procStats.IncludeTop.ByCPU == 0 && procStats.IncludeTop.ByMemory == 0 && procStats.IncludeTop.ByIO == 0) {
 
 		return processes
 	}
@@ -374,6 +573,23 @@ func (procStats *Stats) includeTopProcesses(processes []ProcState) []ProcState {
 		}
 	}
 
+	if procStats.IncludeTop.ByIO > 0 {
+		numProcs := procStats.IncludeTop.ByIO
+		if len(processes) < procStats.IncludeTop.ByIO {
+			numProcs = len(processes)
+		}
+
+		sort.Slice(processes, func(i, j int) bool {
+			return processes[i].IO.diskRate() > processes[j].IO.diskRate()
+		})
+		for _, proc := range processes[:numProcs] {
+			proc := proc
+			if !isProcessInSlice(result, &proc) {
+				result = append(result, proc)
+			}
+		}
+	}
+
 	return result
 }
 
@@ -81,6 +81,7 @@ func GetInfoForPid(_ resolve.Resolver, pid int) (ProcState, error) {
 	state.Name = C.GoString(&info.pi_comm[0])
 	state.Ppid = opt.IntWith(int(info.pi_ppid))
 	state.Pgid = opt.IntWith(int(info.pi_pgrp))
+	state.CPU.StartTime = unixTimeMsToTime(uint64(info.pi_start) * 1000)
 
 	switch info.pi_state {
 	case C.SACTIVE:
@@ -22,6 +22,7 @@ package process
 import (
 	"errors"
 	"fmt"
+	"runtime"
 	"sync"
 
 	"github.com/elastic/elastic-agent-libs/logp"
@@ -41,8 +42,9 @@ type ProcsMap map[int]ProcState
 
 // ProcsTrack is a thread-safe wrapper for a process Stat object's internal map of processes.
 type ProcsTrack struct {
-	pids ProcsMap
-	mut  sync.RWMutex
+	pids   ProcsMap
+	hasMap bool
+	mut    sync.RWMutex
 }
 
 func NewProcsTrack() *ProcsTrack {
@@ -58,6 +60,17 @@ func (pm *ProcsTrack) GetPid(pid int) (ProcState, bool) {
 	return proc, ok
 }
 
+// GetProcess returns the tracked state of the given process.
+// A tracked process with the same PID but a different start time is one that held the PID before
+// it was reused, and is not returned.
+func (pm *ProcsTrack) GetProcess(proc ProcState) (ProcState, bool) {
+	last, ok := pm.GetPid(proc.Pid.ValueOr(0))
+	if !ok || !isSameProcess(last, proc) {
+		return ProcState{}, false
+	}
+	return last, true
+}
+
 func (pm *ProcsTrack) SetPid(pid int, ps ProcState) {
 	pm.mut.Lock()
 	defer pm.mut.Unlock()
@@ -68,7 +81,38 @@ func (pm *ProcsTrack) SetMap(pids map[int]ProcState) {
 	pm.mut.Lock()
 	defer pm.mut.Unlock()
 	pm.pids = pids
+	pm.hasMap = true
+}
+
+// SwapMap replaces the tracked processes and returns the previous map.
+// The second return value is false if no map was set before.
+func (pm *ProcsTrack) SwapMap(pids ProcsMap) (ProcsMap, bool) {
+	pm.mut.Lock()
+	defer pm.mut.Unlock()
+	prev, hadMap := pm.pids, pm.hasMap
+	pm.pids = pids
+	pm.hasMap = true
+	return prev, hadMap
+}
+
+// Snapshot returns a copy of the currently tracked processes.
+func (pm *ProcsTrack) Snapshot() ProcsMap {
+	pm.mut.RLock()
+	defer pm.mut.RUnlock()
+	snap := make(ProcsMap, len(pm.pids))
+	for pid, proc := range pm.pids {
+		snap[pid] = proc
+	}
+	return snap
+}
 
+// isSameProcess returns false if the two states have different start times, meaning the PID was reused.
+// States without a start time are assumed to be the same process.
+func isSameProcess(s0, s1 ProcState) bool {
+	if s0.CPU.StartTime == "" || s1.CPU.StartTime == "" {
+		return true
+	}
+	return s0.CPU.StartTime == s1.CPU.StartTime
 }
 
 // ProcCallback is a function that FetchPid* methods can call at various points to do OS-agnostic processing
@@ -84,6 +128,9 @@ type CgroupPctStats struct {
 	CPUSystemPctNorm float64
 }
 
+// defaultExeHashMaxSize is the default value of Stats.ExeHashMaxSize
+const defaultExeHashMaxSize = 100 << 20
+
 // Stats stores the stats of processes on the host.
 type Stats struct {
 	Hostfs        resolve.Resolver
@@ -99,13 +146,80 @@ type Stats struct {
 	// NetworkMetrics is an allowlist of network metrics,
 	// the names of which can be found in /proc/PID/net/snmp and /proc/PID/net/netstat
 	NetworkMetrics []string
+	// EnableThreads enables per-thread metrics from /proc/PID/task. Linux only.
+	EnableThreads bool
+	// EnableMemoryDetail enables PSS, USS and swap metrics from /proc/PID/smaps_rollup. Linux only.
+	EnableMemoryDetail bool
+	// EnableIO enables I/O counters and rates from /proc/PID/io. Linux only.
+	EnableIO bool
+	// EnableSchedStats enables context switch, run queue wait and page fault counters and rates. Linux only.
+	EnableSchedStats bool
+	// EnableScheduling enables the session, controlling terminal, scheduling policy, priority, nice value
+	// and last CPU of a process, from /proc/PID/stat. Linux only.
+	EnableScheduling bool
+	// EnableFDDetail enables a breakdown of open file descriptors by type. Linux only.
+	EnableFDDetail bool
+	// FDTopPaths is the number of most-referenced file descriptor paths to report when EnableFDDetail is set.
+	FDTopPaths int
+	// EnableSockets enables the per-process inventory of listening sockets and TCP connections,
+	// by matching the sockets in /proc/PID/fd against the /proc/net tables. Linux only.
+	EnableSockets bool
+	// EnableLimits enables the resource limits from /proc/PID/limits, and the usage of
+	// the limits that have a matching metric. Linux only.
+	EnableLimits bool
+	// EnableNamespaces enables the namespace IDs from /proc/PID/ns, and the PID of the process
+	// in each nested PID namespace. Linux only.
+	EnableNamespaces bool
+	// EnableSecurity enables the capabilities, seccomp mode and tracer from /proc/PID/status,
+	// and the SELinux or AppArmor label from /proc/PID/attr/current. Linux only.
+	EnableSecurity bool
+	// EnableUserDetail enables the real, effective and saved users and groups, and the supplementary groups.
+	// Names are resolved from the passwd and group files under Hostfs. Linux only.
+	EnableUserDetail bool
+	// EnablePlacement enables the CPUs and NUMA nodes a process is allowed to use. Linux only.
+	EnablePlacement bool
+	// EnableNUMAMemory adds the memory of a process per NUMA node from /proc/PID/numa_maps when EnablePlacement is set.
+	// This walks the page tables of every process, and is expensive for processes with a large address space.
+	EnableNUMAMemory bool
+	// NormalizeCPUByAffinity normalizes CPU percentages by the number of CPUs a process is allowed to run on,
+	// rather than by the number of CPUs of the host. Enables EnablePlacement.
+	NormalizeCPUByAffinity bool
+	// EnableOOMScore enables the OOM killer score from /proc/PID/oom_score and /proc/PID/oom_score_adj. Linux only.
+	EnableOOMScore bool
+	// EnableExeCheck enables the detection of deleted, memory-backed and replaced executables. Linux only.
+	EnableExeCheck bool
+	// EnableExeHash enables the SHA-256 hash and GNU build ID of process executables.
+	// Each executable is read once, and cached for as long as it is in use. Linux only.
+	EnableExeHash bool
+	// ExeHashMaxSize is the size in bytes above which executables are not hashed when EnableExeHash is set,
+	// they only get a build ID. Defaults to 100 MiB, a negative value disables the limit.
+	ExeHashMaxSize int64
+	// EnableLibraries enables the inventory of shared libraries mapped by each process, from /proc/PID/maps. Linux only.
+	EnableLibraries bool
+	// ExcludeKernelThreads skips kernel threads before any metrics are collected for them. Linux only.
+	ExcludeKernelThreads bool
+	// StuckThreshold is the number of consecutive samples a process has to spend in the DiskSleep or Stopped state
+	// before its wait channel and kernel stack are collected. Zero disables the tracking of stuck processes. Linux only.
+	StuckThreshold int
+	// EnableAncestry adds the ancestor chain, the session and entry leaders, and process.entity_id to the root events.
+	// The session leader requires EnableScheduling, which is enabled as well.
+	// Entity IDs are derived from the host ID, and are not reported if it can't be read.
+	EnableAncestry bool
 
 	skipExtended bool
 	procRegexps  []match.Matcher // List of regular expressions used to whitelist processes.
 	envRegexps   []match.Matcher // List of regular expressions used to whitelist env vars.
 	cgroups      *cgroup.Reader
+	sockets      *socketCache
+	exeHashes    *exeHashCache
+	hostNS       map[string]uint64
 	logger       *logp.Logger
 	host         types.Host
+	// noHostIDLogged is set once a missing host ID was logged
+	noHostIDLogged bool
+
+	// events from the last call to Get()
+	lifecycleEvents []LifecycleEvent
 }
 
 // PidState are the constants for various PID states
@@ -171,7 +285,73 @@ func (procStats *Stats) Init() error {
 		procStats.logger.Warnf("Collecting all network metrics per-process; this will produce a large volume of data.")
 	}
 
+	// the number of allowed CPUs is part of the placement metrics
+	if procStats.NormalizeCPUByAffinity && !procStats.EnablePlacement {
+		procStats.logger.Infof("NormalizeCPUByAffinity requires the CPU affinity of processes, CPU and memory placement will be enabled")
+		procStats.EnablePlacement = true
+	}
+
+	// the session of a process is read along with its scheduling policy
+	if procStats.EnableAncestry && !procStats.EnableScheduling {
+		procStats.logger.Infof("EnableAncestry requires the session of processes, scheduling policies will be enabled")
+		procStats.EnableScheduling = true
+	}
+
+	// Some optional metrics are read from files that only exist in the linux procfs
+	if runtime.GOOS != "linux" {
+		for _, option := range []struct {
+			enabled *bool
+			name    string
+		}{
+			{&procStats.EnableThreads, "EnableThreads"},
+			{&procStats.EnableMemoryDetail, "EnableMemoryDetail"},
+			{&procStats.EnableIO, "EnableIO"},
+			{&procStats.EnableSchedStats, "EnableSchedStats"},
+			{&procStats.EnableScheduling, "EnableScheduling"},
+			{&procStats.EnableFDDetail, "EnableFDDetail"},
+			{&procStats.EnableSockets, "EnableSockets"},
+			{&procStats.EnableLimits, "EnableLimits"},
+			{&procStats.EnableNamespaces, "EnableNamespaces"},
+			{&procStats.EnableSecurity, "EnableSecurity"},
+			{&procStats.EnableUserDetail, "EnableUserDetail"},
+			{&procStats.EnablePlacement, "EnablePlacement"},
+			{&procStats.EnableOOMScore, "EnableOOMScore"},
+			{&procStats.EnableExeCheck, "EnableExeCheck"},
+			{&procStats.EnableExeHash, "EnableExeHash"},
+			{&procStats.EnableLibraries, "EnableLibraries"},
+			{&procStats.ExcludeKernelThreads, "ExcludeKernelThreads"},
+		} {
+			if *option.enabled {
+				procStats.logger.Warnf("%s is only available on linux, it will be disabled", option.name)
+				*option.enabled = false
+			}
+		}
+		if procStats.StuckThreshold > 0 {
+			procStats.logger.Warnf("StuckThreshold is only available on linux, stuck process tracking will be disabled")
+			procStats.StuckThreshold = 0
+		}
+	}
+
 	procStats.ProcsMap = NewProcsTrack()
+	if procStats.EnableSockets {
+		procStats.sockets = newSocketCache()
+	}
+	if procStats.EnableExeHash {
+		if procStats.ExeHashMaxSize == 0 {
+			procStats.ExeHashMaxSize = defaultExeHashMaxSize
+		}
+		procStats.exeHashes = newExeHashCache(procStats.ExeHashMaxSize)
+	}
+	if procStats.EnableNamespaces {
+		// The namespaces of PID 1 are those of the host, or of the container we run in.
+		// Reading them requires the same access as for any other process, so this is a soft error.
+		hostNS, err := getNamespaceIDs(procStats.Hostfs, 1)
+		if err != nil {
+			procStats.logger.Debugf("error fetching namespaces of pid 1, namespaces will not be compared to the host: %s", err)
+		} else {
+			procStats.hostNS = hostNS
+		}
+	}
 
 	if len(procStats.Procs) == 0 {
 		return nil
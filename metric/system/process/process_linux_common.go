@@ -25,7 +25,6 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"os/user"
 	"strconv"
 	"strings"
 	"syscall"
@@ -34,15 +33,13 @@ import (
 	"github.com/elastic/elastic-agent-libs/mapstr"
 	"github.com/elastic/elastic-agent-libs/opt"
 	"github.com/elastic/elastic-agent-system-metrics/metric/system/resolve"
+	"github.com/elastic/elastic-agent-system-metrics/metric/system/sysconf"
 )
 
 // Indulging in one non-const global variable for the sake of storing boot time
 // This value obviously won't change while this code is running.
 var bootTime uint64 = 0
 
-// system tick multiplier, see C.sysconf(C._SC_CLK_TCK)
-const ticks = 100
-
 // FetchPids is the linux implementation of FetchPids
 func (procStats *Stats) FetchPids() (ProcsMap, []ProcState, error) {
 	dir, err := os.Open(procStats.Hostfs.ResolveHostFS("proc"))
@@ -89,7 +86,11 @@ func FillPidMetrics(hostfs resolve.Resolver, pid int, state ProcState, filter fu
 	}
 
 	// CPU Data
-	state.CPU, err = getCPUTime(hostfs, pid)
+	state.files.stat, err = state.files.readStat(hostfs, pid)
+	if err != nil {
+		return state, fmt.Errorf("error getting CPU data for pid %d: %w", pid, err)
+	}
+	state.CPU, err = getCPUTime(hostfs, pid, state.files.stat)
 	if err != nil {
 		return state, fmt.Errorf("error getting CPU data for pid %d: %w", pid, err)
 	}
@@ -104,10 +105,12 @@ func FillPidMetrics(hostfs resolve.Resolver, pid int, state ProcState, filter fu
 	}
 
 	// FD metrics
-	state.FD, err = getFDStats(hostfs, pid)
+	var limits ProcResourceLimits
+	state.FD, limits, err = getFDStats(hostfs, pid)
 	if err != nil {
 		return state, fmt.Errorf("error getting FD metrics for pid %d: %w", pid, err)
 	}
+	state.files.limits = &limits
 
 	if state.Env == nil {
 		// env vars
@@ -119,13 +122,37 @@ func FillPidMetrics(hostfs resolve.Resolver, pid int, state ProcState, filter fu
 		return state, fmt.Errorf("error getting metadata for pid %d: %w", pid, err)
 	}
 
-	state.Username, err = getUser(hostfs, pid)
+	state.files.status, err = state.files.readStatus(hostfs, pid)
+	if err == nil {
+		state.Username, err = userFromStatus(hostfs, state.files.status)
+	}
 	if err != nil {
 		return state, fmt.Errorf("error creating username for pid %d: %w", pid, err)
 	}
 	return state, nil
 }
 
+// readStat returns the contents of /proc/[PID]/stat, unless FillPidMetrics already read them
+func (files procFiles) readStat(hostfs resolve.Resolver, pid int) ([]byte, error) {
+	if files.stat != nil {
+		return files.stat, nil
+	}
+	path := hostfs.Join("proc", strconv.Itoa(pid), "stat")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening file %s: %w", path, err)
+	}
+	return data, nil
+}
+
+// readStatus returns the parsed /proc/[PID]/status, unless FillPidMetrics already read it
+func (files procFiles) readStatus(hostfs resolve.Resolver, pid int) (map[string]string, error) {
+	if files.status != nil {
+		return files.status, nil
+	}
+	return getProcStatus(hostfs, pid)
+}
+
 // GetInfoForPid fetches and parses the process information of the process
 // identified by pid from /proc/[PID]/stat
 func GetInfoForPid(hostFS resolve.Resolver, pid int) (ProcState, error) {
@@ -145,43 +172,98 @@ func GetInfoForPid(hostFS resolve.Resolver, pid int) (ProcState, error) {
 		return state, fmt.Errorf("failed to parse information for pid %d': %w", pid, err)
 	}
 
+	// The start time is needed to tell processes apart if a PID is reused, before any
+	// previous state is looked up.
+	state.CPU.StartTime, err = parseProcStartTime(hostFS, data)
+	if err != nil {
+		return state, fmt.Errorf("failed to get start time for pid %d: %w", pid, err)
+	}
+
 	return state, nil
 }
 
-func parseProcStat(data []byte) (ProcState, error) {
+// parseProcStartTime returns the formatted start time from the contents of /proc/[PID]/stat
+func parseProcStartTime(hostfs resolve.Resolver, data []byte) (string, error) {
+	_, fields, err := splitProcStat(data)
+	if err != nil {
+		return "", err
+	}
+	startTicks, err := strconv.ParseUint(string(fields[19]), 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("error parsing start time value %s: %w", fields[19], err)
+	}
+	return ticksToStartTime(hostfs, startTicks)
+}
+
+// ticksToStartTime converts a start time in clock ticks since boot into a formatted timestamp
+func ticksToStartTime(hostfs resolve.Resolver, startTicks uint64) (string, error) {
+	btime, err := getLinuxBootTime(hostfs)
+	if err != nil {
+		return "", err
+	}
+	// keep the full tick resolution, so that processes started within the same second
+	// can be told apart
+	return unixTimeMsToTime(btime*1000 + ticksToMillis(hostfs, startTicks)), nil
+}
+
+// ticksToMillis converts a value in clock ticks (USER_HZ) into milliseconds
+func ticksToMillis(hostfs resolve.Resolver, ticks uint64) uint64 {
+	return ticks * 1000 / sysconf.ClockTicks(hostfs)
+}
+
+// splitProcStat splits the contents of a /proc/[PID]/stat or /proc/[PID]/task/[TID]/stat file
+// into the comm value and the remaining fields, starting with the state field.
+func splitProcStat(data []byte) (string, [][]byte, error) {
 	const minFields = 36
-	state := ProcState{}
 
 	// Extract the comm value with is surrounded by parentheses.
 	lIdx := bytes.Index(data, []byte("("))
 	rIdx := bytes.LastIndex(data, []byte(")"))
 	if lIdx < 0 || rIdx < 0 || lIdx >= rIdx || rIdx+2 >= len(data) {
-		return state, fmt.Errorf("failed to extract 'comm' field from '%v'",
+		return "", nil, fmt.Errorf("failed to extract 'comm' field from '%v'",
 			string(data))
 	}
-	state.Name = string(data[lIdx+1 : rIdx])
+	comm := string(data[lIdx+1 : rIdx])
 
 	// Extract the rest of the fields that we are interested in.
 	fields := bytes.Fields(data[rIdx+2:])
 	if len(fields) <= minFields {
-		return state, fmt.Errorf("expected at least %d stat fields from '%v'",
+		return comm, nil, fmt.Errorf("expected at least %d stat fields from '%v'",
 			minFields, string(data))
 	}
 
+	return comm, fields, nil
+}
+
+// pfKthread is the PF_KTHREAD flag set on kernel threads, see include/linux/sched.h
+const pfKthread = 0x00200000
+
+func parseProcStat(data []byte) (ProcState, error) {
+	state := ProcState{}
+
+	comm, fields, err := splitProcStat(data)
+	state.Name = comm
+	if err != nil {
+		return state, err
+	}
+
 	// See https://man7.org/linux/man-pages/man5/proc.5.html for all fields.
 	interests := bytes.Join([][]byte{
 		fields[0],  // state
 		fields[1],  // ppid
 		fields[2],  // pgrp
+		fields[6],  // flags
 		fields[17], // num_threads
 	}, []byte(" "))
 
 	var procState string
 	var ppid, pgid, numThreads int
-	_, err := fmt.Fscan(bytes.NewBuffer(interests),
+	var flags uint64
+	_, err = fmt.Fscan(bytes.NewBuffer(interests),
 		&procState,
 		&ppid,
 		&pgid,
+		&flags,
 		&numThreads,
 	)
 	if err != nil {
@@ -193,6 +275,7 @@ func parseProcStat(data []byte) (ProcState, error) {
 	state.Ppid = opt.IntWith(ppid)
 	state.Pgid = opt.IntWith(pgid)
 	state.NumThreads = opt.IntWith(numThreads)
+	state.KernelThread = flags&pfKthread != 0
 
 	return state, nil
 }
@@ -227,20 +310,21 @@ func getUser(hostfs resolve.Resolver, pid int) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("error fetching user ID for pid %d: %w", pid, err)
 	}
+	return userFromStatus(hostfs, status)
+}
+
+// userFromStatus returns the name of the real user of a process from its /proc/[PID]/status
+func userFromStatus(hostfs resolve.Resolver, status map[string]string) (string, error) {
 	uidValues, ok := status["Uid"]
 	if !ok {
-		return "", fmt.Errorf("field Uid not found in proc status: %w", err)
+		return "", errors.New("field Uid not found in proc status")
 	}
 	uidStrings := strings.Fields(uidValues)
-	var userFinal string
-	user, err := user.LookupId(uidStrings[0])
-	if err == nil {
-		userFinal = user.Username
-	} else {
-		userFinal = uidStrings[0]
+	if name := newUserDB(hostfs).userName(uidStrings[0]); name != "" {
+		return name, nil
 	}
 
-	return userFinal, nil
+	return uidStrings[0], nil
 }
 
 func getEnvData(hostfs resolve.Resolver, pid int, filter func(string) bool) (mapstr.M, error) {
@@ -282,33 +366,31 @@ func getMemData(hostfs resolve.Resolver, pid int) (ProcMemInfo, error) {
 	}
 
 	fields := strings.Fields(string(data))
+	// statm counts pages
+	pageSize := sysconf.PageSize(hostfs)
 
 	size, err := strconv.ParseUint(fields[0], 10, 64)
 	if err != nil {
 		return state, fmt.Errorf("error parsing memory size %s: %w", fields[0], err)
 	}
-	state.Size = opt.UintWith(size << 12)
+	state.Size = opt.UintWith(size * pageSize)
 
 	rss, err := strconv.ParseUint(fields[1], 10, 64)
 	if err != nil {
 		return state, fmt.Errorf("error parsing memory rss %s: %w", fields[1], err)
 	}
-	state.Rss.Bytes = opt.UintWith(rss << 12)
+	state.Rss.Bytes = opt.UintWith(rss * pageSize)
 
 	share, _ := strconv.ParseUint(fields[2], 10, 64)
-	state.Share = opt.UintWith(share << 12)
+	state.Share = opt.UintWith(share * pageSize)
 
 	return state, nil
 }
 
-func getCPUTime(hostfs resolve.Resolver, pid int) (ProcCPUInfo, error) {
+// getCPUTime parses the CPU times and start time from the contents of /proc/[PID]/stat
+func getCPUTime(hostfs resolve.Resolver, pid int, data []byte) (ProcCPUInfo, error) {
 	state := ProcCPUInfo{}
 
-	pathCPU := hostfs.Join("proc", strconv.Itoa(pid), "stat")
-	data, err := os.ReadFile(pathCPU)
-	if err != nil {
-		return state, fmt.Errorf("error opening file %s: %w", pathCPU, err)
-	}
 	fields := strings.Fields(string(data))
 
 	user, err := strconv.ParseUint(fields[13], 10, 64)
@@ -320,15 +402,10 @@ func getCPUTime(hostfs resolve.Resolver, pid int) (ProcCPUInfo, error) {
 		return state, fmt.Errorf("error parsing system CPU times for pid %d: %w", pid, err)
 	}
 
-	btime, err := getLinuxBootTime(hostfs)
-	if err != nil {
-		return state, fmt.Errorf("error feting boot time for pid %d: %w", pid, err)
-	}
-
 	// convert to milliseconds from USER_HZ
 	// This effectively means our definition of "ticks" throughout the process code is a millisecond
-	state.User.Ticks = opt.UintWith(user * (1000 / ticks))
-	state.System.Ticks = opt.UintWith(sys * (1000 / ticks))
+	state.User.Ticks = opt.UintWith(ticksToMillis(hostfs, user))
+	state.System.Ticks = opt.UintWith(ticksToMillis(hostfs, sys))
 	state.Total.Ticks = opt.UintWith(opt.SumOptUint(state.User.Ticks, state.System.Ticks))
 
 	startTime, err := strconv.ParseUint(fields[21], 10, 64)
@@ -336,11 +413,10 @@ func getCPUTime(hostfs resolve.Resolver, pid int) (ProcCPUInfo, error) {
 		return state, fmt.Errorf("error parsing start time value %s for pid %d: %w", fields[21], pid, err)
 	}
 
-	startTime /= ticks
-	startTime += btime
-	startTime *= 1000
-
-	state.StartTime = unixTimeMsToTime(startTime)
+	state.StartTime, err = ticksToStartTime(hostfs, startTime)
+	if err != nil {
+		return state, fmt.Errorf("error feting boot time for pid %d: %w", pid, err)
+	}
 	return state, nil
 }
 
@@ -366,45 +442,103 @@ func getArgs(hostfs resolve.Resolver, pid int) ([]string, error) {
 	return args, nil
 }
 
-func getFDStats(hostfs resolve.Resolver, pid int) (ProcFDInfo, error) {
+// getFDStats returns the open file descriptors and their limit, and all the limits of /proc/[PID]/limits
+func getFDStats(hostfs resolve.Resolver, pid int) (ProcFDInfo, ProcResourceLimits, error) {
 	state := ProcFDInfo{}
 
+	limits, err := readLimits(hostfs, pid)
+	if err != nil {
+		return state, limits, err
+	}
+	state.Limit.Soft = limits.OpenFiles.Soft
+	state.Limit.Hard = limits.OpenFiles.Hard
+
+	pathFD := hostfs.Join("proc", strconv.Itoa(pid), "fd")
+	fds, err := os.ReadDir(pathFD)
+	if errors.Is(err, os.ErrPermission) { // ignore permission errors, passthrough other data
+		return state, limits, nil
+	} else if err != nil {
+		return state, limits, fmt.Errorf("error reading FD directory for pid %d: %w", pid, err)
+	}
+	state.Open = opt.UintWith(uint64(len(fds)))
+	return state, limits, nil
+}
+
+// readLimits reads and parses /proc/[PID]/limits
+func readLimits(hostfs resolve.Resolver, pid int) (ProcResourceLimits, error) {
 	path := hostfs.Join("proc", strconv.Itoa(pid), "limits")
 	data, err := os.ReadFile(path)
 	if err != nil {
-		return state, fmt.Errorf("error opening file %s: %w", path, err)
+		return ProcResourceLimits{}, fmt.Errorf("error opening file %s: %w", path, err)
 	}
+	limits, err := parseLimits(data)
+	if err != nil {
+		return limits, fmt.Errorf("error parsing limits for pid %d: %w", pid, err)
+	}
+	return limits, nil
+}
 
+// parseLimits parses the contents of /proc/[PID]/limits
+func parseLimits(data []byte) (ProcResourceLimits, error) {
+	limits := ProcResourceLimits{}
+	rows := map[string]*ResourceLimit{
+		"Max cpu time":          &limits.CPUTime,
+		"Max file size":         &limits.FileSize,
+		"Max data size":         &limits.Data,
+		"Max stack size":        &limits.Stack,
+		"Max core file size":    &limits.Core,
+		"Max resident set":      &limits.RSS,
+		"Max processes":         &limits.Processes,
+		"Max open files":        &limits.OpenFiles,
+		"Max locked memory":     &limits.LockedMemory,
+		"Max address space":     &limits.AddressSpace,
+		"Max file locks":        &limits.FileLocks,
+		"Max pending signals":   &limits.PendingSignals,
+		"Max msgqueue size":     &limits.MsgQueue,
+		"Max nice priority":     &limits.Nice,
+		"Max realtime priority": &limits.RTPriority,
+		"Max realtime timeout":  &limits.RTTime,
+	}
+
+	// The name column is padded to 25 characters, and may contain spaces
+	const nameLen = 25
 	for _, line := range strings.Split(string(data), "\n") {
-		if strings.HasPrefix(line, "Max open files") {
-			fields := strings.Fields(line)
-			if len(fields) == 6 {
-
-				softLimit, err := strconv.ParseUint(fields[3], 10, 64)
-				if err != nil {
-					return state, fmt.Errorf("error parsing limits value %s for pid %d: %w", fields[3], pid, err)
-				}
-				state.Limit.Soft = opt.UintWith(softLimit)
-
-				hardLimit, err := strconv.ParseUint(fields[4], 10, 64)
-				if err != nil {
-					return state, fmt.Errorf("error parsing limits value %s for pid %d: %w", fields[3], pid, err)
-				}
-				state.Limit.Hard = opt.UintWith(hardLimit)
-			}
+		if len(line) <= nameLen {
+			continue
+		}
+		limit, ok := rows[strings.TrimSpace(line[:nameLen])]
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(line[nameLen:])
+		if len(fields) < 2 {
+			return limits, fmt.Errorf("expected soft and hard values in limits line '%s'", line)
+		}
 
+		var err error
+		limit.Soft, limit.SoftUnlimited, err = parseLimitValue(fields[0])
+		if err != nil {
+			return limits, err
+		}
+		limit.Hard, limit.HardUnlimited, err = parseLimitValue(fields[1])
+		if err != nil {
+			return limits, err
 		}
 	}
 
-	pathFD := hostfs.Join("proc", strconv.Itoa(pid), "fd")
-	fds, err := os.ReadDir(pathFD)
-	if errors.Is(err, os.ErrPermission) { // ignore permission errors, passthrough other data
-		return state, nil
-	} else if err != nil {
-		return state, fmt.Errorf("error reading FD directory for pid %d: %w", pid, err)
+	return limits, nil
+}
+
+// parseLimitValue parses a single limit value, which is either a number or "unlimited"
+func parseLimitValue(value string) (opt.Uint, bool, error) {
+	if value == "unlimited" {
+		return opt.NewUintNone(), true, nil
 	}
-	state.Open = opt.UintWith(uint64(len(fds)))
-	return state, nil
+	limit, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return opt.NewUintNone(), false, fmt.Errorf("error parsing limits value %s: %w", value, err)
+	}
+	return opt.UintWith(limit), false, nil
 }
 
 // getLinuxBootTime fetches the static unix time for when the system was booted.
@@ -107,8 +107,17 @@ func TestGetInfoForPid_NumThreads(t *testing.T) {
 		Ppid:       opt.IntWith(1),
 		Pgid:       opt.IntWith(4067478),
 		NumThreads: opt.IntWith(26),
+		CPU: ProcCPUInfo{
+			// btime from testdata/proc/stat plus 200791940 ticks
+			StartTime: "2023-06-16T17:45:21.400Z",
+		},
 	}
 
+	// the boot time is cached for the whole package, read it from testdata/proc/stat
+	prevBootTime := bootTime
+	bootTime = 0
+	t.Cleanup(func() { bootTime = prevBootTime })
+
 	got, err := GetInfoForPid(resolve.NewTestResolver("testdata"), 42)
 	require.NoError(t, err, "GetInfoForPid returned an error when it should have succeeded")
 
@@ -133,3 +142,29 @@ func TestParseProcStat(t *testing.T) {
 
 	assert.Equal(t, want, got, "")
 }
+
+func TestParseProcStatKernelThread(t *testing.T) {
+	data := []byte("2 (kthreadd) S 0 0 0 0 -1 2129984 0 0 0 0 0 1 0 0 20 0 1 0 6 0 0 " +
+		"18446744073709551615 0 0 0 0 0 0 0 2147483647 0 0 0 0 0 1 0 0 0 0 0 0 0 0 0 0 0 0 0")
+
+	got, err := parseProcStat(data)
+	require.NoError(t, err)
+	assert.True(t, got.KernelThread)
+	assert.Equal(t, "kthreadd", got.Name)
+}
+
+func TestExcludeKernelThreads(t *testing.T) {
+	// kthreadd is PID 2 unless we run in a PID namespace
+	kthreadd, err := GetInfoForPid(resolve.NewTestResolver("/"), 2)
+	if err != nil || !kthreadd.KernelThread {
+		t.Skip("kthreadd is not visible")
+	}
+
+	stat, err := initTestResolver()
+	require.NoError(t, err)
+	stat.ExcludeKernelThreads = true
+
+	_, saved, err := stat.pidFill(2, true)
+	require.NoError(t, err)
+	assert.False(t, saved)
+}
@@ -304,6 +304,44 @@ func TestMatchProcs(t *testing.T) {
 	assert.False(t, procStats.matchProcess("burn"))
 }
 
+func TestProcsTrackPidReuse(t *testing.T) {
+	procStats := Stats{CacheCmdLine: true}
+	procStats.ProcsMap = NewProcsTrack()
+
+	old := ProcState{
+		Pid:     opt.IntWith(3456),
+		Args:    []string{"make", "-j8"},
+		Cmdline: "make -j8",
+		CPU:     ProcCPUInfo{StartTime: "2023-05-24T12:00:01.120Z"},
+	}
+	procStats.ProcsMap.SetPid(3456, old)
+
+	last, ok := procStats.ProcsMap.GetProcess(ProcState{Pid: opt.IntWith(3456), CPU: ProcCPUInfo{StartTime: "2023-05-24T12:00:01.120Z"}})
+	assert.True(t, ok)
+	assert.Equal(t, old, last)
+
+	// no start time to compare against
+	_, ok = procStats.ProcsMap.GetProcess(ProcState{Pid: opt.IntWith(3456)})
+	assert.True(t, ok)
+
+	reused := ProcState{
+		Pid: opt.IntWith(3456),
+		CPU: ProcCPUInfo{StartTime: "2023-05-24T12:00:01.570Z"},
+	}
+	_, ok = procStats.ProcsMap.GetProcess(reused)
+	assert.False(t, ok)
+
+	cached := procStats.cacheCmdLine(reused)
+	assert.Empty(t, cached.Args)
+	assert.Empty(t, cached.Cmdline)
+
+	old.CPU.Total.Ticks = opt.UintWith(5000)
+	old.SampleTime = time.Now()
+	reused.CPU.Total.Ticks = opt.UintWith(10)
+	reused.SampleTime = old.SampleTime.Add(time.Second)
+	assert.False(t, GetProcCPUPercentage(old, reused).CPU.Total.Pct.Exists())
+}
+
 func TestProcMemPercentage(t *testing.T) {
 	procStats := Stats{}
 
@@ -356,6 +394,86 @@ func TestProcCpuPercentage(t *testing.T) {
 	assert.EqualValues(t, 3.459, newState.CPU.Total.Pct.ValueOr(0))
 }
 
+func TestProcCpuPercentageByAffinity(t *testing.T) {
+	p1 := ProcState{
+		CPU: ProcCPUInfo{
+			Total: CPUTotal{Ticks: opt.UintWith(10000)},
+		},
+		SampleTime: time.Now(),
+	}
+	// two cores busy for a second, with the process pinned to those cores
+	p2 := ProcState{
+		CPU: ProcCPUInfo{
+			Total: CPUTotal{Ticks: opt.UintWith(12000)},
+		},
+		Placement:  ProcPlacement{CPUsAllowedCount: opt.IntWith(2)},
+		SampleTime: p1.SampleTime.Add(time.Second),
+	}
+
+	newState := GetProcCPUPercentageByAffinity(p1, p2)
+	assert.EqualValues(t, 2, newState.CPU.Total.Pct.ValueOr(0))
+	assert.EqualValues(t, 1, newState.CPU.Total.Norm.Pct.ValueOr(0))
+
+	// without affinity data, this falls back to the number of CPUs of the host
+	p2.Placement = ProcPlacement{}
+	assert.Equal(t, GetProcCPUPercentage(p1, p2), GetProcCPUPercentageByAffinity(p1, p2))
+}
+
+func TestRankByOOMScore(t *testing.T) {
+	proc := func(pid, score, adj int, rss uint64) ProcState {
+		return ProcState{
+			Pid:    opt.IntWith(pid),
+			Memory: ProcMemInfo{Rss: MemBytePct{Bytes: opt.UintWith(rss)}},
+			OOM:    ProcOOMInfo{Score: opt.IntWith(score), ScoreAdj: opt.IntWith(adj)},
+		}
+	}
+	procs := []ProcState{
+		proc(1, 0, -1000, 100),
+		proc(2, 300, 0, 100),
+		proc(3, 800, 500, 50),
+		proc(4, 300, 0, 400),
+		{Pid: opt.IntWith(5)},
+	}
+
+	var pids []int
+	for _, p := range RankByOOMScore(procs) {
+		pids = append(pids, p.Pid.ValueOr(0))
+	}
+	assert.Equal(t, []int{3, 4, 2}, pids)
+	assert.Equal(t, 1, procs[0].Pid.ValueOr(0), "input was reordered")
+}
+
+func TestListAnomalousExecutables(t *testing.T) {
+	procs := []ProcState{
+		{Pid: opt.IntWith(30), ExeState: ProcExeState{MemoryBacked: true}},
+		{Pid: opt.IntWith(10)},
+		{Pid: opt.IntWith(20), ExeState: ProcExeState{Deleted: true}},
+		{Pid: opt.IntWith(5), ExeState: ProcExeState{InodeMismatch: true}},
+	}
+
+	var pids []int
+	for _, p := range ListAnomalousExecutables(procs) {
+		pids = append(pids, p.Pid.ValueOr(0))
+	}
+	assert.Equal(t, []int{5, 20, 30}, pids)
+}
+
+func TestProcCgroupPercentage(t *testing.T) {
+	proc := ProcState{
+		Memory: ProcMemInfo{Rss: MemBytePct{Bytes: opt.UintWith(256 << 20)}},
+		CPU:    ProcCPUInfo{Total: CPUTotal{Pct: opt.FloatWith(1.5)}},
+	}
+
+	got := GetProcCgroupPercentage(proc, 1<<30, 2)
+	assert.Equal(t, opt.FloatWith(0.25), got.Memory.Rss.Cgroup.Pct)
+	assert.Equal(t, opt.FloatWith(0.75), got.CPU.Total.Cgroup.Pct)
+
+	// no limits
+	got = GetProcCgroupPercentage(proc, 0, 0)
+	assert.True(t, got.Memory.Rss.Cgroup.IsZero())
+	assert.True(t, got.CPU.Total.Cgroup.IsZero())
+}
+
 func TestIncludeTopProcesses(t *testing.T) {
 	processes := []ProcState{
 		{
@@ -576,6 +694,34 @@ func TestIncludeTopProcesses(t *testing.T) {
 	}
 }
 
+func TestIncludeTopProcessesByIO(t *testing.T) {
+	ioProc := func(pid int, read, write float64) ProcState {
+		return ProcState{
+			Pid: opt.IntWith(pid),
+			IO: ProcIOInfo{
+				ReadBytes:  Counter{Rate: opt.FloatWith(read)},
+				WriteBytes: Counter{Rate: opt.FloatWith(write)},
+			},
+		}
+	}
+	processes := []ProcState{
+		ioProc(1, 100, 0),
+		ioProc(2, 0, 5000),
+		ioProc(3, 0, 0),
+		ioProc(4, 2000, 2000),
+		{Pid: opt.IntWith(5)},
+	}
+
+	procStats := Stats{IncludeTop: IncludeTopConfig{Enabled: true, ByIO: 2}}
+	res := procStats.includeTopProcesses(processes)
+
+	resPids := []int{}
+	for _, p := range res {
+		resPids = append(resPids, p.Pid.ValueOr(0))
+	}
+	assert.Equal(t, []int{2, 4}, resPids)
+}
+
 // runThreads run the threads binary for the current GOOS.
 //
 //go:generate docker run --rm -v ./testdata:/app --entrypoint g++ docker.elastic.co/beats-dev/golang-crossbuild:1.21.0-main -pthread -std=c++11 -o /app/threads /app/threads.cpp
@@ -663,3 +809,17 @@ func sliceContains(s []string, e string) bool {
 
 	return false
 }
+
+func TestProcStateContainer(t *testing.T) {
+	_, ok := ProcState{}.Container()
+	assert.False(t, ok)
+
+	want := cgroup.ContainerInfo{Runtime: cgroup.RuntimeDocker, ID: "b29faf21b7ef"}
+	got, ok := ProcState{Cgroup: &cgroup.StatsV2{Container: want}}.Container()
+	assert.True(t, ok)
+	assert.Equal(t, want, got)
+
+	got, ok = ProcState{Cgroup: &cgroup.StatsV1{Container: want}}.Container()
+	assert.True(t, ok)
+	assert.Equal(t, want, got)
+}
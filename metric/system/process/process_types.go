@@ -18,6 +18,7 @@
 package process
 
 import (
+	"strings"
 	"time"
 
 	"github.com/elastic/elastic-agent-libs/mapstr"
@@ -29,13 +30,16 @@ import (
 // ProcState is the main struct for process information and metrics.
 type ProcState struct {
 	// Basic Process data
-	Name       string   `struct:"name,omitempty"`
-	State      PidState `struct:"state,omitempty"`
-	Username   string   `struct:"username,omitempty"`
-	Pid        opt.Int  `struct:"pid,omitempty"`
-	Ppid       opt.Int  `struct:"ppid,omitempty"`
-	Pgid       opt.Int  `struct:"pgid,omitempty"`
-	NumThreads opt.Int  `struct:"num_threads,omitempty"`
+	Name         string   `struct:"name,omitempty"`
+	State        PidState `struct:"state,omitempty"`
+	Username     string   `struct:"username,omitempty"`
+	Pid          opt.Int  `struct:"pid,omitempty"`
+	Ppid         opt.Int  `struct:"ppid,omitempty"`
+	Pgid         opt.Int  `struct:"pgid,omitempty"`
+	NumThreads   opt.Int  `struct:"num_threads,omitempty"`
+	Session      opt.Int  `struct:"session,omitempty"`
+	TTY          string   `struct:"tty,omitempty"`
+	KernelThread bool     `struct:"kernel_thread,omitempty"`
 
 	// Extended Process Data
 	Args    []string `struct:"args,omitempty"`
@@ -44,17 +48,61 @@ type ProcState struct {
 	Exe     string   `struct:"exe,omitempty"`
 	Env     mapstr.M `struct:"env,omitempty"`
 
+	// Scheduling
+	Scheduling ProcSchedulingInfo `struct:"scheduling,omitempty"`
+	OOM        ProcOOMInfo        `struct:"oom,omitempty"`
+	Wait       ProcWaitInfo       `struct:"wait,omitempty"`
+
 	// Resource Metrics
 	Memory  ProcMemInfo                       `struct:"memory,omitempty"`
 	CPU     ProcCPUInfo                       `struct:"cpu,omitempty"`
 	FD      ProcFDInfo                        `struct:"fd,omitempty"`
+	IO      ProcIOInfo                        `struct:"io,omitempty"`
+	Sched   ProcSchedInfo                     `struct:"sched,omitempty"`
+	Socket  ProcSocketInfo                    `struct:"socket,omitempty"`
+	Limits  ProcResourceLimits                `struct:"limits,omitempty"`
 	Network *sysinfotypes.NetworkCountersInfo `struct:"-,omitempty"`
 
+	// Namespaces, only populated when namespace collection is enabled
+	Namespaces ProcNamespaces `struct:"namespaces,omitempty"`
+	// Security context, only populated when security collection is enabled
+	Security ProcSecurity `struct:"security,omitempty"`
+	// Users and groups, only populated when user detail collection is enabled
+	Owner ProcOwner `struct:"owner,omitempty"`
+	// CPU and memory placement, only populated when placement collection is enabled
+	Placement ProcPlacement `struct:"placement,omitempty"`
+	// State of the running executable, only populated when executable checks are enabled
+	ExeState ProcExeState `struct:"exe_state,omitempty"`
+	// Hash and GNU build ID of the executable, only populated when executable hashing is enabled.
+	// The hash is moved to the ECS process.hash field of root events, the build ID is not an ECS field.
+	Hash    ProcHash `struct:"hash,omitempty"`
+	BuildID string   `struct:"build_id,omitempty"`
+	// Shared objects mapped by the process, only populated when library collection is enabled
+	Libraries []SharedLibrary `struct:"libraries,omitempty"`
+
+	// Ancestors, session and entry leader, only populated when ancestry collection is enabled.
+	// They are moved to the root event by FormatForRoot.
+	EntityID string       `struct:"entity_id,omitempty"`
+	Ancestry ProcAncestry `struct:"ancestry,omitempty"`
+
+	// Per-thread metrics, only populated when thread collection is enabled
+	Threads []ThreadState `struct:"threads,omitempty"`
+
 	// cgroups
 	Cgroup cgroup.CGStats `struct:"cgroup,omitempty"`
 
 	// meta
 	SampleTime time.Time `struct:"-,omitempty"`
+	// files read by FillPidMetrics, only kept while the process is filled
+	files procFiles
+}
+
+// procFiles holds the contents of the /proc/[PID]/stat and status files, and the parsed limits, read by FillPidMetrics,
+// so that the optional metrics parsed from the same files don't read them again
+type procFiles struct {
+	stat   []byte
+	status map[string]string
+	limits *ProcResourceLimits
 }
 
 // ProcCPUInfo is the main struct for CPU metrics
@@ -66,6 +114,16 @@ type ProcCPUInfo struct {
 	System CPUTicks `struct:"system,omitempty"`
 }
 
+// ThreadState is the struct for per-thread metrics, read from /proc/[PID]/task/[TID]/stat
+type ThreadState struct {
+	Tid   opt.Int  `struct:"tid,omitempty"`
+	Name  string   `struct:"name,omitempty"`
+	State PidState `struct:"state,omitempty"`
+	// Processor is the CPU the thread last ran on
+	Processor opt.Int     `struct:"processor,omitempty"`
+	CPU       ProcCPUInfo `struct:"cpu,omitempty"`
+}
+
 // CPUTicks is a formatting wrapper for `tick` metric values
 type CPUTicks struct {
 	Ticks opt.Uint `struct:"ticks,omitempty"`
@@ -77,6 +135,8 @@ type CPUTotal struct {
 	Ticks opt.Uint   `struct:"ticks,omitempty"`
 	Pct   opt.Float  `struct:"pct,omitempty"`
 	Norm  opt.PctOpt `struct:"norm,omitempty"`
+	// Cgroup is the CPU usage relative to the CPU quota or cpuset of the cgroup of the process
+	Cgroup opt.PctOpt `struct:"cgroup,omitempty"`
 }
 
 // ProcMemInfo is the struct for cpu.memory metrics
@@ -84,18 +144,117 @@ type ProcMemInfo struct {
 	Size  opt.Uint   `struct:"size,omitempty"`
 	Share opt.Uint   `struct:"share,omitempty"`
 	Rss   MemBytePct `struct:"rss,omitempty"`
+
+	// Optional detailed metrics, from /proc/[PID]/smaps_rollup
+	// Pss is the proportional set size, where shared pages are divided between the processes mapping them.
+	Pss opt.Uint `struct:"pss,omitempty"`
+	// Uss is the unique set size, the memory that would be freed if the process exited.
+	Uss       opt.Uint `struct:"uss,omitempty"`
+	Anonymous opt.Uint `struct:"anonymous,omitempty"`
+	// File and Shmem are the proportional share of file-backed and shared memory pages.
+	// On kernels without smaps_rollup they are summed up by mapping, and tmpfs files outside of /dev/shm count as files.
+	File  opt.Uint `struct:"file,omitempty"`
+	Shmem opt.Uint `struct:"shmem,omitempty"`
+	Swap  opt.Uint `struct:"swap,omitempty"`
+
+	// Optional page fault counters, from /proc/[PID]/stat
+	PageFaults PageFaults `struct:"page_faults,omitempty"`
+}
+
+// PageFaults wraps the minor and major page fault counters
+type PageFaults struct {
+	Minor Counter `struct:"minor,omitempty"`
+	Major Counter `struct:"major,omitempty"`
+}
+
+// ProcSchedInfo is the struct for process.sched metrics
+type ProcSchedInfo struct {
+	// Context switches, from /proc/[PID]/status
+	VoluntaryCtxSwitches   Counter `struct:"voluntary_context_switches,omitempty"`
+	InvoluntaryCtxSwitches Counter `struct:"involuntary_context_switches,omitempty"`
+	// Time spent on the CPU and waiting on a run queue in nanoseconds, from /proc/[PID]/schedstat
+	CPUTimeNS      Counter `struct:"cpu_time_ns,omitempty"`
+	RunqueueWaitNS Counter `struct:"runqueue_wait_ns,omitempty"`
+	Timeslices     Counter `struct:"timeslices,omitempty"`
 }
 
 // MemBytePct is the formatting struct for wrapping pct/byte metrics
 type MemBytePct struct {
 	Bytes opt.Uint  `struct:"bytes,omitempty"`
 	Pct   opt.Float `struct:"pct,omitempty"`
+	// Cgroup is the memory usage relative to the memory limit of the cgroup of the process
+	Cgroup opt.PctOpt `struct:"cgroup,omitempty"`
+}
+
+// ProcIOInfo is the struct for process.io metrics, from /proc/[PID]/io
+type ProcIOInfo struct {
+	// Bytes passed to read() and write() style syscalls, including reads served from the page cache
+	ReadChar  Counter `struct:"read_char,omitempty"`
+	WriteChar Counter `struct:"write_char,omitempty"`
+	// Count of read() and write() style syscalls
+	ReadSyscalls  Counter `struct:"read_syscalls,omitempty"`
+	WriteSyscalls Counter `struct:"write_syscalls,omitempty"`
+	// Bytes fetched from, or sent to the storage layer
+	ReadBytes           Counter `struct:"read_bytes,omitempty"`
+	WriteBytes          Counter `struct:"write_bytes,omitempty"`
+	CancelledWriteBytes Counter `struct:"cancelled_write_bytes,omitempty"`
+}
+
+// Counter is the formatting struct for wrapping a monotonic counter and its per-second rate
+type Counter struct {
+	Total opt.Uint  `struct:"total,omitempty"`
+	Rate  opt.Float `struct:"rate,omitempty"`
+}
+
+// ProcSocketInfo is the struct for process.socket metrics
+type ProcSocketInfo struct {
+	Listening []ListeningSocket `struct:"listening,omitempty"`
+	// Connections is the count of TCP connections by state, not including listening sockets
+	Connections map[string]int `struct:"connections,omitempty"`
+}
+
+// ListeningSocket is a single listening socket held open by a process
+type ListeningSocket struct {
+	// Protocol is one of tcp, tcp6, udp, udp6 or unix
+	Protocol string `struct:"protocol"`
+	Address  string `struct:"address,omitempty"`
+	Port     int    `struct:"port,omitempty"`
+	// Path is the bound path of a unix socket
+	Path string `struct:"path,omitempty"`
 }
 
 // ProcFDInfo is the struct for process.fd metrics
 type ProcFDInfo struct {
 	Open  opt.Uint   `struct:"open,omitempty"`
 	Limit ProcLimits `struct:"limit,omitempty"`
+
+	// Optional breakdown of the open file descriptors
+	Types    FDTypes       `struct:"types,omitempty"`
+	TopPaths []FDPathCount `struct:"top_paths,omitempty"`
+}
+
+// FDTypes wraps the count of open file descriptors by type
+type FDTypes struct {
+	File    opt.Uint `struct:"file,omitempty"`
+	Socket  opt.Uint `struct:"socket,omitempty"`
+	Pipe    opt.Uint `struct:"pipe,omitempty"`
+	Device  opt.Uint `struct:"device,omitempty"`
+	Deleted opt.Uint `struct:"deleted,omitempty"`
+	// anonymous memory files created with memfd_create(2)
+	MemFD opt.Uint `struct:"memfd,omitempty"`
+	// anon_inode types
+	EventFD   opt.Uint `struct:"eventfd,omitempty"`
+	Epoll     opt.Uint `struct:"epoll,omitempty"`
+	Inotify   opt.Uint `struct:"inotify,omitempty"`
+	TimerFD   opt.Uint `struct:"timerfd,omitempty"`
+	AnonInode opt.Uint `struct:"anon_inode,omitempty"`
+	Other     opt.Uint `struct:"other,omitempty"`
+}
+
+// FDPathCount is the number of file descriptors referencing a single path
+type FDPathCount struct {
+	Path  string `struct:"path"`
+	Count int    `struct:"count"`
 }
 
 // ProcLimits wraps the fd.limit metrics
@@ -104,10 +263,204 @@ type ProcLimits struct {
 	Hard opt.Uint `struct:"hard,omitempty"`
 }
 
+// ProcResourceLimits contains the resource limits of a process, read from /proc/[PID]/limits.
+// Values are in the units used by the kernel: seconds for CPUTime, microseconds for RTTime,
+// a count for Processes, OpenFiles, FileLocks and PendingSignals, and bytes for the rest.
+type ProcResourceLimits struct {
+	CPUTime        ResourceLimit `struct:"cpu_time,omitempty"`
+	FileSize       ResourceLimit `struct:"file_size,omitempty"`
+	Data           ResourceLimit `struct:"data,omitempty"`
+	Stack          ResourceLimit `struct:"stack,omitempty"`
+	Core           ResourceLimit `struct:"core,omitempty"`
+	RSS            ResourceLimit `struct:"rss,omitempty"`
+	Processes      ResourceLimit `struct:"processes,omitempty"`
+	OpenFiles      ResourceLimit `struct:"open_files,omitempty"`
+	LockedMemory   ResourceLimit `struct:"locked_memory,omitempty"`
+	AddressSpace   ResourceLimit `struct:"address_space,omitempty"`
+	FileLocks      ResourceLimit `struct:"file_locks,omitempty"`
+	PendingSignals ResourceLimit `struct:"pending_signals,omitempty"`
+	MsgQueue       ResourceLimit `struct:"msgqueue,omitempty"`
+	Nice           ResourceLimit `struct:"nice,omitempty"`
+	RTPriority     ResourceLimit `struct:"rtprio,omitempty"`
+	RTTime         ResourceLimit `struct:"rttime,omitempty"`
+}
+
+// ResourceLimit is a single soft and hard resource limit.
+// An unlimited value is reported with the Unlimited flag, and no value.
+type ResourceLimit struct {
+	Soft          opt.Uint `struct:"soft,omitempty"`
+	SoftUnlimited bool     `struct:"soft_unlimited,omitempty"`
+	Hard          opt.Uint `struct:"hard,omitempty"`
+	HardUnlimited bool     `struct:"hard_unlimited,omitempty"`
+	// Pct is the current usage as a fraction of the soft limit, for limits with a matching usage metric
+	Pct opt.Float `struct:"pct,omitempty"`
+}
+
+// ProcNamespaces contains the namespaces of a process, read from /proc/[PID]/ns
+// and the NSpid and NStgid fields of /proc/[PID]/status.
+type ProcNamespaces struct {
+	PID    Namespace `struct:"pid,omitempty"`
+	Net    Namespace `struct:"net,omitempty"`
+	Mnt    Namespace `struct:"mnt,omitempty"`
+	UTS    Namespace `struct:"uts,omitempty"`
+	IPC    Namespace `struct:"ipc,omitempty"`
+	User   Namespace `struct:"user,omitempty"`
+	Cgroup Namespace `struct:"cgroup,omitempty"`
+	Time   Namespace `struct:"time,omitempty"`
+
+	// NSpid is the PID of the process in each nested PID namespace, starting with the outermost one.
+	// The last value is the PID as seen inside a container.
+	NSpid []int `struct:"nspid,omitempty"`
+	// NStgid is the thread group ID of the process in each nested PID namespace.
+	NStgid []int `struct:"nstgid,omitempty"`
+}
+
+// Namespace identifies a single namespace of a process
+type Namespace struct {
+	// ID is the inode number of the namespace
+	ID opt.Uint `struct:"id,omitempty"`
+	// DiffersFromHost is true if the namespace is not the one of PID 1.
+	// It is nil if the namespaces of PID 1 are not known.
+	DiffersFromHost *bool `struct:"differs_from_host,omitempty"`
+}
+
+// ProcSecurity contains the security context of a process, read from /proc/[PID]/status and /proc/[PID]/attr/current
+type ProcSecurity struct {
+	Capabilities ProcCapabilities `struct:"capabilities,omitempty"`
+	// Seccomp is the seccomp mode: disabled, strict or filter
+	Seccomp    string `struct:"seccomp,omitempty"`
+	NoNewPrivs bool   `struct:"no_new_privs,omitempty"`
+	// TracerPid is the PID of the process tracing this process, or 0 if it is not being traced
+	TracerPid opt.Int `struct:"tracer_pid,omitempty"`
+	// Label is the SELinux context or AppArmor profile of the process
+	Label string `struct:"label,omitempty"`
+}
+
+// ProcCapabilities contains the names of the capabilities in each of the capability sets of a process
+type ProcCapabilities struct {
+	Inheritable []string `struct:"inheritable,omitempty"`
+	Permitted   []string `struct:"permitted,omitempty"`
+	Effective   []string `struct:"effective,omitempty"`
+	Bounding    []string `struct:"bounding,omitempty"`
+	Ambient     []string `struct:"ambient,omitempty"`
+}
+
+// ProcOwner contains the users and groups a process runs as, read from /proc/[PID]/status
+type ProcOwner struct {
+	User               OwnerID   `struct:"user,omitempty"` // effective user
+	RealUser           OwnerID   `struct:"real_user,omitempty"`
+	SavedUser          OwnerID   `struct:"saved_user,omitempty"`
+	Group              OwnerID   `struct:"group,omitempty"` // effective group
+	RealGroup          OwnerID   `struct:"real_group,omitempty"`
+	SavedGroup         OwnerID   `struct:"saved_group,omitempty"`
+	SupplementalGroups []OwnerID `struct:"supplemental_groups,omitempty"`
+}
+
+// OwnerID is a user or group ID, and its name if it could be resolved
+type OwnerID struct {
+	ID   string `struct:"id,omitempty"`
+	Name string `struct:"name,omitempty"`
+}
+
+// ProcPlacement contains the CPUs and NUMA nodes a process is allowed to use, read from /proc/[PID]/status,
+// and optionally where its memory is placed, read from /proc/[PID]/numa_maps.
+type ProcPlacement struct {
+	// CPUsAllowed is the list of CPUs the process may run on, such as 0-3,8
+	CPUsAllowed      string  `struct:"cpus_allowed,omitempty"`
+	CPUsAllowedCount opt.Int `struct:"cpus_allowed_count,omitempty"`
+	// MemsAllowed is the list of NUMA nodes the process may allocate memory on
+	MemsAllowed      string  `struct:"mems_allowed,omitempty"`
+	MemsAllowedCount opt.Int `struct:"mems_allowed_count,omitempty"`
+
+	NUMANodes []NUMANodeMemory `struct:"numa_nodes,omitempty"`
+}
+
+// NUMANodeMemory is the memory of a process that is placed on a single NUMA node
+type NUMANodeMemory struct {
+	Node  int    `struct:"node"`
+	Pages uint64 `struct:"pages"`
+	Bytes uint64 `struct:"bytes"`
+}
+
+// ProcSchedulingInfo contains the scheduling policy and priorities of a process, read from /proc/[PID]/stat
+type ProcSchedulingInfo struct {
+	// Policy is the scheduling policy, such as SCHED_OTHER or SCHED_FIFO
+	Policy     string  `struct:"policy,omitempty"`
+	Priority   opt.Int `struct:"priority,omitempty"`
+	Nice       opt.Int `struct:"nice,omitempty"`
+	RTPriority opt.Int `struct:"rt_priority,omitempty"`
+	// Processor is the CPU the process last ran on
+	Processor opt.Int `struct:"processor,omitempty"`
+}
+
+// ProcOOMInfo contains the OOM killer score of a process, read from /proc/[PID]/oom_score and /proc/[PID]/oom_score_adj
+type ProcOOMInfo struct {
+	// Score is the badness score used to select a process to kill, higher values are more likely to be killed
+	Score opt.Int `struct:"score,omitempty"`
+	// ScoreAdj is the user-set adjustment of the score, from -1000 (never kill) to 1000
+	ScoreAdj opt.Int `struct:"score_adj,omitempty"`
+}
+
+// ProcWaitInfo tracks processes that stay blocked in the DiskSleep or Stopped state
+type ProcWaitInfo struct {
+	// StuckSamples is the number of consecutive samples the process was found blocked
+	StuckSamples opt.Int `struct:"stuck_samples,omitempty"`
+	// Channel is the kernel function the process is waiting in, from /proc/[PID]/wchan
+	Channel string `struct:"channel,omitempty"`
+	// Stack is the kernel stack of the process, from /proc/[PID]/stack
+	Stack []string `struct:"stack,omitempty"`
+}
+
+// ProcExeState describes how the running executable of a process relates to the file at its path
+type ProcExeState struct {
+	// Deleted is set if the executable was removed or replaced since the process started.
+	// Replacing a file by renaming another one over it, as package managers do, unlinks it,
+	// so upgraded binaries are reported as deleted.
+	Deleted bool `struct:"deleted,omitempty"`
+	// MemoryBacked is set if the executable is a memfd or anonymous memory, and has no file on disk
+	MemoryBacked bool `struct:"memory_backed,omitempty"`
+	// InodeMismatch is set if the file at the executable path is not the one the process is running,
+	// while the executable itself is still linked. This happens when another file is mounted over the path,
+	// or when the path resolves to another file in the mount namespace of the process.
+	InodeMismatch bool `struct:"inode_mismatch,omitempty"`
+}
+
+// ProcAncestry contains the processes a process descends from
+type ProcAncestry struct {
+	// Ancestors is the parent chain, starting with the direct parent,
+	// up to PID 1 or the init process of the container the process runs in.
+	// It is not an ECS field, ECS only defines the parent and the session, entry and group leaders.
+	Ancestors     []ProcessRef `struct:"ancestors,omitempty"`
+	SessionLeader ProcessRef   `struct:"session_leader,omitempty"`
+	EntryLeader   ProcessRef   `struct:"entry_leader,omitempty"`
+}
+
+// ProcessRef identifies a related process, using the ECS process fields
+type ProcessRef struct {
+	Pid      opt.Int `struct:"pid,omitempty"`
+	Name     string  `struct:"name,omitempty"`
+	Start    string  `struct:"start,omitempty"`
+	EntityID string  `struct:"entity_id,omitempty"`
+}
+
+// ProcHash contains the hashes of the executable of a process
+type ProcHash struct {
+	SHA256 string `struct:"sha256,omitempty"`
+}
+
+// SharedLibrary is a shared object mapped into the address space of a process
+type SharedLibrary struct {
+	Path string `struct:"path"`
+	// Deleted is set if the file was removed or replaced after it was loaded
+	Deleted bool `struct:"deleted"`
+	// Size is the total size of all mappings of the file
+	Size opt.Uint `struct:"size,omitempty"`
+}
+
 // Implementations
 
 func (t CPUTotal) IsZero() bool {
-	return t.Value.IsZero() && t.Ticks.IsZero() && t.Pct.IsZero() && t.Norm.IsZero()
+	return t.Value.IsZero() && t.Ticks.IsZero() && t.Pct.IsZero() && t.Norm.IsZero() && t.Cgroup.IsZero()
 }
 
 // IsZero returns true if the underlying value nil
@@ -115,9 +468,170 @@ func (t CPUTicks) IsZero() bool {
 	return t.Ticks.IsZero()
 }
 
+// IsZero returns true if the underlying value nil
+func (c Counter) IsZero() bool {
+	return c.Total.IsZero() && c.Rate.IsZero()
+}
+
+// IsZero returns true if none of the I/O counters are set
+func (t ProcIOInfo) IsZero() bool {
+	return t.ReadChar.IsZero() && t.WriteChar.IsZero() && t.ReadSyscalls.IsZero() && t.WriteSyscalls.IsZero() &&
+		t.ReadBytes.IsZero() && t.WriteBytes.IsZero() && t.CancelledWriteBytes.IsZero()
+}
+
+// IsZero returns true if none of the page fault counters are set
+func (t PageFaults) IsZero() bool {
+	return t.Minor.IsZero() && t.Major.IsZero()
+}
+
+// IsZero returns true if none of the scheduler counters are set
+func (t ProcSchedInfo) IsZero() bool {
+	return t.VoluntaryCtxSwitches.IsZero() && t.InvoluntaryCtxSwitches.IsZero() &&
+		t.CPUTimeNS.IsZero() && t.RunqueueWaitNS.IsZero() && t.Timeslices.IsZero()
+}
+
+// IsZero returns true if the process holds no listening sockets or connections
+func (t ProcSocketInfo) IsZero() bool {
+	return len(t.Listening) == 0 && len(t.Connections) == 0
+}
+
+// diskRate returns the combined read and write rate to the storage layer, used for top-N filtering
+func (t ProcIOInfo) diskRate() float64 {
+	return t.ReadBytes.Rate.ValueOr(0) + t.WriteBytes.Rate.ValueOr(0)
+}
+
 // IsZero returns true if the underlying value nil
 func (t ProcFDInfo) IsZero() bool {
-	return t.Open.IsZero() && t.Limit.Hard.IsZero() && t.Limit.Soft.IsZero()
+	return t.Open.IsZero() && t.Limit.Hard.IsZero() && t.Limit.Soft.IsZero() && t.Types.IsZero() && len(t.TopPaths) == 0
+}
+
+// IsZero returns true if no limit is set
+func (l ResourceLimit) IsZero() bool {
+	return l.Soft.IsZero() && !l.SoftUnlimited && l.Hard.IsZero() && !l.HardUnlimited && l.Pct.IsZero()
+}
+
+// IsZero returns true if none of the limits are set
+func (t ProcResourceLimits) IsZero() bool {
+	return t.CPUTime.IsZero() && t.FileSize.IsZero() && t.Data.IsZero() && t.Stack.IsZero() && t.Core.IsZero() &&
+		t.RSS.IsZero() && t.Processes.IsZero() && t.OpenFiles.IsZero() && t.LockedMemory.IsZero() && t.AddressSpace.IsZero() &&
+		t.FileLocks.IsZero() && t.PendingSignals.IsZero() && t.MsgQueue.IsZero() && t.Nice.IsZero() && t.RTPriority.IsZero() && t.RTTime.IsZero()
+}
+
+// IsZero returns true if the namespace ID is not set
+func (n Namespace) IsZero() bool {
+	return n.ID.IsZero()
+}
+
+// IsZero returns true if no namespace information is set
+func (t ProcNamespaces) IsZero() bool {
+	return t.PID.IsZero() && t.Net.IsZero() && t.Mnt.IsZero() && t.UTS.IsZero() && t.IPC.IsZero() &&
+		t.User.IsZero() && t.Cgroup.IsZero() && t.Time.IsZero() && len(t.NSpid) == 0 && len(t.NStgid) == 0
+}
+
+// IsZero returns true if no security data is set
+func (t ProcSecurity) IsZero() bool {
+	return t.Capabilities.IsZero() && t.Seccomp == "" && !t.NoNewPrivs && t.TracerPid.IsZero() && t.Label == ""
+}
+
+// IsZero returns true if all capability sets are empty
+func (t ProcCapabilities) IsZero() bool {
+	return len(t.Inheritable) == 0 && len(t.Permitted) == 0 && len(t.Effective) == 0 && len(t.Bounding) == 0 && len(t.Ambient) == 0
+}
+
+// HasEffectiveCapability returns true if the given capability, such as CAP_SYS_ADMIN, is in the effective set
+func (t ProcSecurity) HasEffectiveCapability(name string) bool {
+	for _, capability := range t.Capabilities.Effective {
+		if capability == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Traced returns true if the process is being traced, for example by a debugger
+func (t ProcSecurity) Traced() bool {
+	return t.TracerPid.ValueOr(0) != 0
+}
+
+// Unconfined returns true if the process runs without an AppArmor profile, or in an unconfined SELinux domain.
+// It is false if no label could be read, as no LSM may be active.
+func (t ProcSecurity) Unconfined() bool {
+	return t.Label == "unconfined" || strings.Contains(t.Label, ":unconfined_t:")
+}
+
+// IsZero returns true if the ID is not set
+func (t OwnerID) IsZero() bool {
+	return t.ID == "" && t.Name == ""
+}
+
+// IsZero returns true if no user or group is set
+func (t ProcOwner) IsZero() bool {
+	return t.User.IsZero() && t.RealUser.IsZero() && t.SavedUser.IsZero() &&
+		t.Group.IsZero() && t.RealGroup.IsZero() && t.SavedGroup.IsZero() && len(t.SupplementalGroups) == 0
+}
+
+// IsZero returns true if no placement data is set
+func (t ProcPlacement) IsZero() bool {
+	return t.CPUsAllowed == "" && t.CPUsAllowedCount.IsZero() && t.MemsAllowed == "" && t.MemsAllowedCount.IsZero() && len(t.NUMANodes) == 0
+}
+
+// IsZero returns true if no scheduling data is set
+func (t ProcSchedulingInfo) IsZero() bool {
+	return t.Policy == "" && t.Priority.IsZero() && t.Nice.IsZero() && t.RTPriority.IsZero() && t.Processor.IsZero()
+}
+
+// IsZero returns true if no OOM score is set
+func (t ProcOOMInfo) IsZero() bool {
+	return t.Score.IsZero() && t.ScoreAdj.IsZero()
+}
+
+// IsZero returns true if the process was not found blocked
+func (t ProcWaitInfo) IsZero() bool {
+	return t.StuckSamples.IsZero() && t.Channel == "" && len(t.Stack) == 0
+}
+
+// IsZero returns true if no ancestry is set
+func (t ProcAncestry) IsZero() bool {
+	return len(t.Ancestors) == 0 && t.SessionLeader.IsZero() && t.EntryLeader.IsZero()
+}
+
+// IsZero returns true if no process is referenced
+func (t ProcessRef) IsZero() bool {
+	return t.Pid.IsZero() && t.Name == "" && t.Start == "" && t.EntityID == ""
+}
+
+// IsZero returns true if no hash is set
+func (t ProcHash) IsZero() bool {
+	return t.SHA256 == ""
+}
+
+// IsZero returns true if none of the executable states are set
+func (t ProcExeState) IsZero() bool {
+	return !t.Anomalous()
+}
+
+// Anomalous returns true if the process is not running the file found at its executable path
+func (t ProcExeState) Anomalous() bool {
+	return t.Deleted || t.MemoryBacked || t.InodeMismatch
+}
+
+// IsZero returns true if none of the type counts are set
+func (t FDTypes) IsZero() bool {
+	return t.File.IsZero() && t.Socket.IsZero() && t.Pipe.IsZero() && t.Device.IsZero() && t.Deleted.IsZero() && t.MemFD.IsZero() &&
+		t.EventFD.IsZero() && t.Epoll.IsZero() && t.Inotify.IsZero() && t.TimerFD.IsZero() && t.AnonInode.IsZero() && t.Other.IsZero()
+}
+
+// Container returns the container and Kubernetes pod the process runs in, from its cgroup paths.
+// It is only set when cgroups are enabled, and is reported with the cgroup metrics as cgroup.container.
+func (p ProcState) Container() (cgroup.ContainerInfo, bool) {
+	var info cgroup.ContainerInfo
+	switch stats := p.Cgroup.(type) {
+	case *cgroup.StatsV1:
+		info = stats.Container
+	case *cgroup.StatsV2:
+		info = stats.Container
+	}
+	return info, !info.IsZero()
 }
 
 func (p *ProcState) FormatForRoot() ProcStateRootEvent {
@@ -132,6 +646,19 @@ func (p *ProcState) FormatForRoot() ProcStateRootEvent {
 	root.Process.Parent.Pid = p.Ppid
 	p.Ppid = opt.NewIntNone()
 
+	root.Process.EntityID = p.EntityID
+	p.EntityID = ""
+	if len(p.Ancestry.Ancestors) > 0 {
+		parent := p.Ancestry.Ancestors[0]
+		root.Process.Parent.Name = parent.Name
+		root.Process.Parent.Start = parent.Start
+		root.Process.Parent.EntityID = parent.EntityID
+	}
+	root.Process.Ancestors = p.Ancestry.Ancestors
+	root.Process.SessionLeader = p.Ancestry.SessionLeader
+	root.Process.EntryLeader = p.Ancestry.EntryLeader
+	p.Ancestry = ProcAncestry{}
+
 	root.Process.Pgid = p.Pgid
 	p.Pgid = opt.NewIntNone()
 
@@ -150,6 +677,9 @@ func (p *ProcState) FormatForRoot() ProcStateRootEvent {
 	root.Process.Exe = p.Exe
 	p.Exe = ""
 
+	root.Process.Hash = p.Hash
+	p.Hash = ProcHash{}
+
 	root.Process.Args = p.Args
 	p.Args = nil
 
@@ -170,15 +700,25 @@ type ProcessRoot struct {
 	Memory  opt.PctOpt    `struct:"memory,omitempty"`
 	Cwd     string        `struct:"working_directory,omitempty"`
 	Exe     string        `struct:"executable,omitempty"`
+	Hash    ProcHash      `struct:"hash,omitempty"`
 	Args    []string      `struct:"args,omitempty"`
 	Name    string        `struct:"name,omitempty"`
 	Pid     opt.Int       `struct:"pid,omitempty"`
 	Parent  Parent        `struct:"parent,omitempty"`
 	Pgid    opt.Int       `struct:"pgid,omitempty"`
+
+	EntityID string `struct:"entity_id,omitempty"`
+	// Ancestors is not an ECS field, it is reported as process.ancestors in addition to the ECS parent and leaders
+	Ancestors     []ProcessRef `struct:"ancestors,omitempty"`
+	SessionLeader ProcessRef   `struct:"session_leader,omitempty"`
+	EntryLeader   ProcessRef   `struct:"entry_leader,omitempty"`
 }
 
 type Parent struct {
-	Pid opt.Int `struct:"pid,omitempty"`
+	Pid      opt.Int `struct:"pid,omitempty"`
+	Name     string  `struct:"name,omitempty"`
+	Start    string  `struct:"start,omitempty"`
+	EntityID string  `struct:"entity_id,omitempty"`
 }
 
 type Name struct {
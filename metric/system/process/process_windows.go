@@ -64,6 +64,18 @@ func GetInfoForPid(_ resolve.Resolver, pid int) (ProcState, error) {
 		state.Name = name
 	}
 
+	// the start time is needed to tell a reused PID from the tracked process,
+	// before the cached command line of the PID is used
+	userTime, sysTime, startTime, err := getProcTimes(pid)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error getting CPU times: %w", err))
+	} else {
+		state.CPU.System.Ticks = opt.UintWith(sysTime)
+		state.CPU.User.Ticks = opt.UintWith(userTime)
+		state.CPU.Total.Ticks = opt.UintWith(userTime + sysTime)
+		state.CPU.StartTime = unixTimeMsToTime(startTime)
+	}
+
 	// system/process doesn't need this here, but system/process_summary does.
 	status, err := getPidStatus(pid)
 	if err != nil {
@@ -124,17 +136,6 @@ func FillPidMetrics(_ resolve.Resolver, pid int, state ProcState, _ func(string)
 	state.Memory.Rss.Bytes = opt.UintWith(wss)
 	state.Memory.Size = opt.UintWith(size)
 
-	userTime, sysTime, startTime, err := getProcTimes(pid)
-	if err != nil {
-		return state, fmt.Errorf("error getting CPU times: %w", err)
-	}
-
-	state.CPU.System.Ticks = opt.UintWith(sysTime)
-	state.CPU.User.Ticks = opt.UintWith(userTime)
-	state.CPU.Total.Ticks = opt.UintWith(userTime + sysTime)
-
-	state.CPU.StartTime = unixTimeMsToTime(startTime)
-
 	return state, nil
 }
 
@@ -0,0 +1,226 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package process
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/elastic-agent-libs/opt"
+	"github.com/elastic/elastic-agent-system-metrics/metric/system/resolve"
+)
+
+// fillSchedStats fills out the context switch counters from /proc/[PID]/status,
+// the run queue counters from /proc/[PID]/schedstat and the page fault counters from /proc/[PID]/stat.
+// The stat and status files already read by FillPidMetrics are passed in with state.
+func fillSchedStats(hostfs resolve.Resolver, pid int, state ProcState) (ProcState, error) {
+	status, err := state.files.readStatus(hostfs, pid)
+	if err != nil {
+		return state, fmt.Errorf("error fetching status for pid %d: %w", pid, err)
+	}
+	state.Sched.VoluntaryCtxSwitches.Total, err = optUintFromStatus(status, "voluntary_ctxt_switches")
+	if err != nil {
+		return state, err
+	}
+	state.Sched.InvoluntaryCtxSwitches.Total, err = optUintFromStatus(status, "nonvoluntary_ctxt_switches")
+	if err != nil {
+		return state, err
+	}
+
+	// schedstat only exists on kernels built with CONFIG_SCHED_INFO
+	path := hostfs.Join("proc", strconv.Itoa(pid), "schedstat")
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return state, fmt.Errorf("error opening file %s: %w", path, err)
+	} else if err == nil {
+		state.Sched, err = parseSchedstat(data, state.Sched)
+		if err != nil {
+			return state, fmt.Errorf("error parsing %s: %w", path, err)
+		}
+	}
+
+	data, err = state.files.readStat(hostfs, pid)
+	if err != nil {
+		return state, err
+	}
+	_, fields, err := splitProcStat(data)
+	if err != nil {
+		return state, fmt.Errorf("error parsing stat for pid %d: %w", pid, err)
+	}
+	// See https://man7.org/linux/man-pages/man5/proc.5.html for all fields.
+	minflt, err := strconv.ParseUint(string(fields[7]), 10, 64)
+	if err != nil {
+		return state, fmt.Errorf("error parsing minor page faults for pid %d: %w", pid, err)
+	}
+	majflt, err := strconv.ParseUint(string(fields[9]), 10, 64)
+	if err != nil {
+		return state, fmt.Errorf("error parsing major page faults for pid %d: %w", pid, err)
+	}
+	state.Memory.PageFaults.Minor.Total = opt.UintWith(minflt)
+	state.Memory.PageFaults.Major.Total = opt.UintWith(majflt)
+
+	return state, nil
+}
+
+// fillScheduling fills out the session, controlling terminal and scheduling policy from /proc/[PID]/stat.
+// The stat file already read by FillPidMetrics is passed in with state.
+func fillScheduling(hostfs resolve.Resolver, pid int, state ProcState) (ProcState, error) {
+	data, err := state.files.readStat(hostfs, pid)
+	if err != nil {
+		return state, err
+	}
+	return parseProcStatScheduling(data, state)
+}
+
+// parseProcStatScheduling parses the session, tty and scheduling fields of /proc/[PID]/stat
+func parseProcStatScheduling(data []byte, state ProcState) (ProcState, error) {
+	_, fields, err := splitProcStat(data)
+	if err != nil {
+		return state, err
+	}
+
+	// See https://man7.org/linux/man-pages/man5/proc.5.html for all fields.
+	// The scheduling fields were added over several kernel releases, and may be missing from other procfs implementations
+	const policyField = 38
+	if len(fields) <= policyField {
+		return state, nil
+	}
+	interests := bytes.Join([][]byte{
+		fields[3],  // session
+		fields[4],  // tty_nr
+		fields[15], // priority
+		fields[16], // nice
+		fields[36], // processor
+		fields[37], // rt_priority
+		fields[38], // policy
+	}, []byte(" "))
+
+	var session, priority, nice, processor, rtPriority int
+	var ttyNr, policy uint64
+	_, err = fmt.Fscan(bytes.NewBuffer(interests),
+		&session,
+		&ttyNr,
+		&priority,
+		&nice,
+		&processor,
+		&rtPriority,
+		&policy,
+	)
+	if err != nil {
+		return state, fmt.Errorf("failed to parse scheduling fields from '%s': %w",
+			string(data), err)
+	}
+
+	state.Session = opt.IntWith(session)
+	state.TTY = ttyName(ttyNr)
+	state.Scheduling = ProcSchedulingInfo{
+		Policy:     schedPolicyName(policy),
+		Priority:   opt.IntWith(priority),
+		Nice:       opt.IntWith(nice),
+		RTPriority: opt.IntWith(rtPriority),
+		Processor:  opt.IntWith(processor),
+	}
+
+	return state, nil
+}
+
+// schedPolicies are the scheduling policies, see sched(7)
+var schedPolicies = map[uint64]string{
+	0: "SCHED_OTHER",
+	1: "SCHED_FIFO",
+	2: "SCHED_RR",
+	3: "SCHED_BATCH",
+	5: "SCHED_IDLE",
+	6: "SCHED_DEADLINE",
+}
+
+func schedPolicyName(policy uint64) string {
+	if name, ok := schedPolicies[policy]; ok {
+		return name
+	}
+	return strconv.FormatUint(policy, 10)
+}
+
+// ttyName decodes the tty_nr field of /proc/[PID]/stat into the name of the terminal under /dev.
+// An empty string is returned for processes without a controlling terminal,
+// and major:minor for terminals that are not virtual consoles, serial ports or pseudoterminals.
+func ttyName(ttyNr uint64) string {
+	if ttyNr == 0 {
+		return ""
+	}
+	// See MAJOR() and MINOR() in linux/kdev_t.h
+	major := (ttyNr >> 8) & 0xfff
+	minor := (ttyNr & 0xff) | ((ttyNr >> 12) & 0xfff00)
+
+	// See Documentation/admin-guide/devices.txt in the kernel sources
+	switch {
+	case major == 4 && minor < 64:
+		return "tty" + strconv.FormatUint(minor, 10)
+	case major == 4:
+		return "ttyS" + strconv.FormatUint(minor-64, 10)
+	case major == 5 && minor == 0:
+		return "tty"
+	case major == 5 && minor == 1:
+		return "console"
+	case major >= 136 && major <= 143:
+		return "pts/" + strconv.FormatUint((major-136)*256+minor, 10)
+	}
+	return fmt.Sprintf("%d:%d", major, minor)
+}
+
+// parseSchedstat parses the three fields of /proc/[PID]/schedstat:
+// time spent on the cpu, time spent waiting on a run queue, and the number of timeslices run
+func parseSchedstat(data []byte, state ProcSchedInfo) (ProcSchedInfo, error) {
+	fields := strings.Fields(string(data))
+	if len(fields) < 3 {
+		return state, fmt.Errorf("expected 3 fields, got %d", len(fields))
+	}
+
+	values := make([]uint64, 3)
+	for i := range values {
+		value, err := strconv.ParseUint(fields[i], 10, 64)
+		if err != nil {
+			return state, fmt.Errorf("error parsing schedstat value %s: %w", fields[i], err)
+		}
+		values[i] = value
+	}
+	state.CPUTimeNS.Total = opt.UintWith(values[0])
+	state.RunqueueWaitNS.Total = opt.UintWith(values[1])
+	state.Timeslices.Total = opt.UintWith(values[2])
+
+	return state, nil
+}
+
+// optUintFromStatus returns the given key of a parsed /proc/[PID]/status file, or None if it does not exist.
+func optUintFromStatus(status map[string]string, key string) (opt.Uint, error) {
+	raw, ok := status[key]
+	if !ok {
+		return opt.NewUintNone(), nil
+	}
+	value, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		return opt.NewUintNone(), fmt.Errorf("error parsing %s value %s: %w", key, raw, err)
+	}
+	return opt.UintWith(value), nil
+}
@@ -0,0 +1,145 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package process
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-libs/opt"
+	"github.com/elastic/elastic-agent-system-metrics/metric/system/resolve"
+)
+
+func TestFillSchedStats(t *testing.T) {
+	got, err := fillSchedStats(resolve.NewTestResolver("testdata"), 42, ProcState{})
+	require.NoError(t, err)
+
+	wantSched := ProcSchedInfo{
+		VoluntaryCtxSwitches:   Counter{Total: opt.UintWith(23861)},
+		InvoluntaryCtxSwitches: Counter{Total: opt.UintWith(1187)},
+		CPUTimeNS:              Counter{Total: opt.UintWith(45123811024)},
+		RunqueueWaitNS:         Counter{Total: opt.UintWith(1638471022)},
+		Timeslices:             Counter{Total: opt.UintWith(94311)},
+	}
+	assert.Equal(t, wantSched, got.Sched)
+
+	wantFaults := PageFaults{
+		Minor: Counter{Total: opt.UintWith(151900)},
+		Major: Counter{Total: opt.UintWith(0)},
+	}
+	assert.Equal(t, wantFaults, got.Memory.PageFaults)
+}
+
+func TestFillSchedStatsReadFiles(t *testing.T) {
+	hostfs := resolve.NewTestResolver("testdata")
+	files := procFiles{}
+	var err error
+	files.stat, err = files.readStat(hostfs, 42)
+	require.NoError(t, err)
+	files.status, err = files.readStatus(hostfs, 42)
+	require.NoError(t, err)
+
+	// the files read by FillPidMetrics are used, there is nothing to read for this pid
+	got, err := fillSchedStats(resolve.NewTestResolver(t.TempDir()), 42, ProcState{files: files})
+	require.NoError(t, err)
+	assert.Equal(t, opt.UintWith(23861), got.Sched.VoluntaryCtxSwitches.Total)
+	assert.Equal(t, opt.UintWith(151900), got.Memory.PageFaults.Minor.Total)
+	assert.False(t, got.Sched.CPUTimeNS.Total.Exists())
+}
+
+func TestProcSchedRate(t *testing.T) {
+	s0 := ProcState{
+		Sched:      ProcSchedInfo{RunqueueWaitNS: Counter{Total: opt.UintWith(1000000)}},
+		SampleTime: time.Now(),
+	}
+	s0.Memory.PageFaults.Minor.Total = opt.UintWith(100)
+	s1 := ProcState{
+		Sched:      ProcSchedInfo{RunqueueWaitNS: Counter{Total: opt.UintWith(251000000)}},
+		SampleTime: s0.SampleTime.Add(500 * time.Millisecond),
+	}
+	s1.Memory.PageFaults.Minor.Total = opt.UintWith(150)
+
+	got := GetProcSchedRate(s0, s1)
+	// 250ms of run queue wait over a 500ms interval
+	assert.Equal(t, float64(500000000), got.Sched.RunqueueWaitNS.Rate.ValueOr(0))
+	assert.Equal(t, float64(100), got.Memory.PageFaults.Minor.Rate.ValueOr(0))
+	assert.False(t, got.Sched.CPUTimeNS.Rate.Exists())
+}
+
+func TestSelfSchedStats(t *testing.T) {
+	stat, err := initTestResolver()
+	require.NoError(t, err)
+	stat.EnableSchedStats = true
+
+	_, err = stat.GetSelf()
+	require.NoError(t, err)
+	time.Sleep(time.Millisecond * 5)
+	self, err := stat.GetSelf()
+	require.NoError(t, err)
+
+	assert.True(t, self.Sched.VoluntaryCtxSwitches.Total.Exists())
+	assert.True(t, self.Sched.VoluntaryCtxSwitches.Rate.Exists())
+	assert.True(t, self.Memory.PageFaults.Minor.Total.Exists())
+}
+
+func TestFillScheduling(t *testing.T) {
+	got, err := fillScheduling(resolve.NewTestResolver("testdata"), 42, ProcState{})
+	require.NoError(t, err)
+
+	assert.Equal(t, opt.IntWith(4067478), got.Session)
+	assert.Equal(t, "", got.TTY)
+	assert.Equal(t, ProcSchedulingInfo{
+		Policy:     "SCHED_OTHER",
+		Priority:   opt.IntWith(32),
+		Nice:       opt.IntWith(12),
+		RTPriority: opt.IntWith(0),
+		Processor:  opt.IntWith(9),
+	}, got.Scheduling)
+}
+
+func TestFillSchedulingReadStat(t *testing.T) {
+	stat, err := procFiles{}.readStat(resolve.NewTestResolver("testdata"), 42)
+	require.NoError(t, err)
+
+	// the stat file read by FillPidMetrics is used, there is nothing to read for this pid
+	got, err := fillScheduling(resolve.NewTestResolver(t.TempDir()), 42, ProcState{files: procFiles{stat: stat}})
+	require.NoError(t, err)
+	assert.Equal(t, opt.IntWith(4067478), got.Session)
+	assert.Equal(t, "SCHED_OTHER", got.Scheduling.Policy)
+}
+
+func TestTTYName(t *testing.T) {
+	assert.Equal(t, "", ttyName(0))
+	assert.Equal(t, "tty1", ttyName(1025))
+	assert.Equal(t, "ttyS0", ttyName(1088))
+	assert.Equal(t, "pts/0", ttyName(34816))
+	assert.Equal(t, "pts/300", ttyName(35116))
+	assert.Equal(t, "console", ttyName(1281))
+	assert.Equal(t, "254:3", ttyName(65027))
+}
+
+func TestSchedPolicyName(t *testing.T) {
+	assert.Equal(t, "SCHED_BATCH", schedPolicyName(3))
+	assert.Equal(t, "SCHED_DEADLINE", schedPolicyName(6))
+	assert.Equal(t, "9", schedPolicyName(9))
+}
@@ -0,0 +1,166 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package process
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/elastic/elastic-agent-libs/opt"
+	"github.com/elastic/elastic-agent-system-metrics/metric/system/resolve"
+)
+
+// capabilityNames are the capability names, indexed by bit number. See capabilities(7).
+var capabilityNames = []string{
+	"CAP_CHOWN",
+	"CAP_DAC_OVERRIDE",
+	"CAP_DAC_READ_SEARCH",
+	"CAP_FOWNER",
+	"CAP_FSETID",
+	"CAP_KILL",
+	"CAP_SETGID",
+	"CAP_SETUID",
+	"CAP_SETPCAP",
+	"CAP_LINUX_IMMUTABLE",
+	"CAP_NET_BIND_SERVICE",
+	"CAP_NET_BROADCAST",
+	"CAP_NET_ADMIN",
+	"CAP_NET_RAW",
+	"CAP_IPC_LOCK",
+	"CAP_IPC_OWNER",
+	"CAP_SYS_MODULE",
+	"CAP_SYS_RAWIO",
+	"CAP_SYS_CHROOT",
+	"CAP_SYS_PTRACE",
+	"CAP_SYS_PACCT",
+	"CAP_SYS_ADMIN",
+	"CAP_SYS_BOOT",
+	"CAP_SYS_NICE",
+	"CAP_SYS_RESOURCE",
+	"CAP_SYS_TIME",
+	"CAP_SYS_TTY_CONFIG",
+	"CAP_MKNOD",
+	"CAP_LEASE",
+	"CAP_AUDIT_WRITE",
+	"CAP_AUDIT_CONTROL",
+	"CAP_SETFCAP",
+	"CAP_MAC_OVERRIDE",
+	"CAP_MAC_ADMIN",
+	"CAP_SYSLOG",
+	"CAP_WAKE_ALARM",
+	"CAP_BLOCK_SUSPEND",
+	"CAP_AUDIT_READ",
+	"CAP_PERFMON",
+	"CAP_BPF",
+	"CAP_CHECKPOINT_RESTORE",
+}
+
+var seccompModes = map[string]string{
+	"0": "disabled",
+	"1": "strict",
+	"2": "filter",
+}
+
+// getSecurity fetches the capabilities, seccomp mode, tracer and no_new_privs flag from /proc/[PID]/status,
+// and the LSM label from /proc/[PID]/attr/current
+func getSecurity(hostfs resolve.Resolver, pid int) (ProcSecurity, error) {
+	state := ProcSecurity{}
+
+	status, err := getProcStatus(hostfs, pid)
+	if err != nil {
+		return state, fmt.Errorf("error fetching status for pid %d: %w", pid, err)
+	}
+
+	sets := []struct {
+		key  string
+		dest *[]string
+	}{
+		{"CapInh", &state.Capabilities.Inheritable},
+		{"CapPrm", &state.Capabilities.Permitted},
+		{"CapEff", &state.Capabilities.Effective},
+		{"CapBnd", &state.Capabilities.Bounding},
+		{"CapAmb", &state.Capabilities.Ambient},
+	}
+	for _, set := range sets {
+		raw, ok := status[set.key]
+		if !ok {
+			continue
+		}
+		*set.dest, err = decodeCapabilities(raw)
+		if err != nil {
+			return state, fmt.Errorf("error parsing %s for pid %d: %w", set.key, pid, err)
+		}
+	}
+
+	state.Seccomp = seccompModes[status["Seccomp"]]
+	state.NoNewPrivs = status["NoNewPrivs"] == "1"
+	if raw, ok := status["TracerPid"]; ok {
+		tracer, err := strconv.Atoi(raw)
+		if err != nil {
+			return state, fmt.Errorf("error parsing TracerPid for pid %d: %w", pid, err)
+		}
+		state.TracerPid = opt.IntWith(tracer)
+	}
+
+	state.Label, err = getLSMLabel(hostfs, pid)
+	if err != nil {
+		return state, err
+	}
+
+	return state, nil
+}
+
+// decodeCapabilities decodes a hexadecimal capability set from /proc/[PID]/status into capability names.
+// Capabilities that are unknown to this library are reported by number, as CAP_<bit>.
+func decodeCapabilities(raw string) ([]string, error) {
+	mask, err := strconv.ParseUint(raw, 16, 64)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for bit := 0; bit < 64; bit++ {
+		if mask&(1<<bit) == 0 {
+			continue
+		}
+		if bit < len(capabilityNames) {
+			names = append(names, capabilityNames[bit])
+		} else {
+			names = append(names, "CAP_"+strconv.Itoa(bit))
+		}
+	}
+	return names, nil
+}
+
+// getLSMLabel reads the SELinux context or AppArmor profile of a process.
+// An empty label is returned if no LSM that provides labels is active.
+func getLSMLabel(hostfs resolve.Resolver, pid int) (string, error) {
+	path := hostfs.Join("proc", strconv.Itoa(pid), "attr", "current")
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) || errors.Is(err, syscall.EINVAL) {
+		return "", nil
+	} else if err != nil {
+		return "", fmt.Errorf("error reading %s: %w", path, err)
+	}
+	return strings.TrimRight(string(data), "\x00\n"), nil
+}
@@ -0,0 +1,73 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package process
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-libs/opt"
+	"github.com/elastic/elastic-agent-system-metrics/metric/system/resolve"
+)
+
+func TestGetSecurity(t *testing.T) {
+	got, err := getSecurity(resolve.NewTestResolver("testdata"), 42)
+	require.NoError(t, err)
+
+	assert.Empty(t, got.Capabilities.Effective)
+	assert.Len(t, got.Capabilities.Bounding, 41)
+	assert.Equal(t, "disabled", got.Seccomp)
+	assert.False(t, got.NoNewPrivs)
+	assert.Equal(t, opt.IntWith(0), got.TracerPid)
+	assert.Equal(t, "system_u:system_r:unconfined_t:s0", got.Label)
+
+	assert.False(t, got.HasEffectiveCapability("CAP_SYS_ADMIN"))
+	assert.False(t, got.Traced())
+	assert.True(t, got.Unconfined())
+}
+
+func TestDecodeCapabilities(t *testing.T) {
+	// CAP_NET_BIND_SERVICE, CAP_SYS_ADMIN and an unknown capability
+	got, err := decodeCapabilities("0000800000200400")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"CAP_NET_BIND_SERVICE", "CAP_SYS_ADMIN", "CAP_47"}, got)
+
+	got, err = decodeCapabilities("0000000000000000")
+	require.NoError(t, err)
+	assert.Empty(t, got)
+
+	_, err = decodeCapabilities("zz")
+	assert.Error(t, err)
+}
+
+func TestProcSecurityHelpers(t *testing.T) {
+	state := ProcSecurity{
+		Capabilities: ProcCapabilities{Effective: []string{"CAP_SYS_ADMIN"}},
+		TracerPid:    opt.IntWith(1234),
+		Label:        "/usr/sbin/cupsd (enforce)",
+	}
+	assert.True(t, state.HasEffectiveCapability("CAP_SYS_ADMIN"))
+	assert.True(t, state.Traced())
+	assert.False(t, state.Unconfined())
+
+	assert.True(t, ProcSecurity{Label: "unconfined"}.Unconfined())
+}
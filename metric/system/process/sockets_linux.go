@@ -0,0 +1,311 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package process
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/elastic/elastic-agent-system-metrics/metric/system/resolve"
+)
+
+// tcpStates maps the hex state codes in /proc/net/tcp to their names, see include/net/tcp_states.h
+var tcpStates = map[string]string{
+	"01": "established",
+	"02": "syn_sent",
+	"03": "syn_recv",
+	"04": "fin_wait1",
+	"05": "fin_wait2",
+	"06": "time_wait",
+	"07": "close",
+	"08": "close_wait",
+	"09": "last_ack",
+	"0A": "listen",
+	"0B": "closing",
+	"0C": "new_syn_recv",
+}
+
+const (
+	// TCP_LISTEN in /proc/net/tcp
+	tcpListen = "0A"
+	// TCP_CLOSE in /proc/net/udp, an unconnected UDP socket
+	udpUnconnected = "07"
+	// __SO_ACCEPTCON in /proc/net/unix
+	unixAcceptCon = 0x10000
+)
+
+// socketEntry is a single socket from one of the /proc/net tables
+type socketEntry struct {
+	protocol string
+	address  string
+	port     int
+	path     string
+	state    string
+	listen   bool
+}
+
+// socketCache holds the parsed socket tables of each network namespace seen during a single scan of /proc,
+// so that processes sharing a namespace don't parse the same tables more than once.
+type socketCache struct {
+	mut    sync.Mutex
+	tables map[string]map[uint64]socketEntry
+}
+
+func newSocketCache() *socketCache {
+	return &socketCache{tables: map[string]map[uint64]socketEntry{}}
+}
+
+// reset drops all cached tables, this should be called at the start of every scan.
+func (sc *socketCache) reset() {
+	sc.mut.Lock()
+	defer sc.mut.Unlock()
+	sc.tables = map[string]map[uint64]socketEntry{}
+}
+
+// getSockets returns the listening sockets and TCP connection counts for the sockets held open by a process
+func (sc *socketCache) getSockets(hostfs resolve.Resolver, pid int) (ProcSocketInfo, error) {
+	info := ProcSocketInfo{}
+	pidStr := strconv.Itoa(pid)
+
+	inodes, err := getSocketInodes(hostfs, pid)
+	if err != nil {
+		return info, err
+	}
+	if len(inodes) == 0 {
+		return info, nil
+	}
+
+	netns, err := os.Readlink(hostfs.Join("proc", pidStr, "ns", "net"))
+	if err != nil {
+		return info, fmt.Errorf("error reading network namespace for pid %d: %w", pid, err)
+	}
+
+	table, err := sc.getTable(hostfs, pid, netns)
+	if err != nil {
+		return info, err
+	}
+
+	for _, inode := range inodes {
+		entry, ok := table[inode]
+		if !ok {
+			continue
+		}
+		if entry.listen {
+			info.Listening = append(info.Listening, ListeningSocket{
+				Protocol: entry.protocol,
+				Address:  entry.address,
+				Port:     entry.port,
+				Path:     entry.path,
+			})
+			continue
+		}
+		if entry.state != "" {
+			if info.Connections == nil {
+				info.Connections = map[string]int{}
+			}
+			info.Connections[entry.state]++
+		}
+	}
+	sort.Slice(info.Listening, func(i, j int) bool {
+		if info.Listening[i].Protocol == info.Listening[j].Protocol {
+			return info.Listening[i].Port < info.Listening[j].Port
+		}
+		return info.Listening[i].Protocol < info.Listening[j].Protocol
+	})
+
+	return info, nil
+}
+
+// getTable returns the socket table for the network namespace, parsing it from the /proc/[PID]/net files of the given pid if it's not cached
+func (sc *socketCache) getTable(hostfs resolve.Resolver, pid int, netns string) (map[uint64]socketEntry, error) {
+	sc.mut.Lock()
+	defer sc.mut.Unlock()
+	if table, ok := sc.tables[netns]; ok {
+		return table, nil
+	}
+
+	table := map[uint64]socketEntry{}
+	for _, protocol := range []string{"tcp", "tcp6", "udp", "udp6"} {
+		path := hostfs.Join("proc", strconv.Itoa(pid), "net", protocol)
+		err := parseNetIPTable(path, protocol, table)
+		if err != nil {
+			return nil, err
+		}
+	}
+	path := hostfs.Join("proc", strconv.Itoa(pid), "net", "unix")
+	err := parseNetUnixTable(path, table)
+	if err != nil {
+		return nil, err
+	}
+
+	sc.tables[netns] = table
+	return table, nil
+}
+
+// getSocketInodes returns the inodes of all sockets in /proc/[PID]/fd
+func getSocketInodes(hostfs resolve.Resolver, pid int) ([]uint64, error) {
+	pathFD := hostfs.Join("proc", strconv.Itoa(pid), "fd")
+	fds, err := os.ReadDir(pathFD)
+	if err != nil {
+		return nil, fmt.Errorf("error reading FD directory for pid %d: %w", pid, err)
+	}
+
+	var inodes []uint64
+	for _, fd := range fds {
+		target, err := os.Readlink(hostfs.Join("proc", strconv.Itoa(pid), "fd", fd.Name()))
+		if err != nil || !strings.HasPrefix(target, "socket:[") {
+			continue
+		}
+		inode, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(target, "socket:["), "]"), 10, 64)
+		if err != nil {
+			continue
+		}
+		inodes = append(inodes, inode)
+	}
+	return inodes, nil
+}
+
+// parseNetIPTable parses one of /proc/net/{tcp,tcp6,udp,udp6} into the table, keyed by inode
+func parseNetIPTable(path string, protocol string, table map[uint64]socketEntry) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) { // ipv6 may be disabled
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("error opening file %s: %w", path, err)
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	// skip the header
+	sc.Scan()
+	for sc.Scan() {
+		// sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 10 {
+			continue
+		}
+		inode, err := strconv.ParseUint(fields[9], 10, 64)
+		if err != nil || inode == 0 {
+			continue
+		}
+		address, port, err := parseNetAddress(fields[1])
+		if err != nil {
+			return fmt.Errorf("error parsing address in %s: %w", path, err)
+		}
+
+		entry := socketEntry{protocol: protocol, address: address, port: port}
+		if strings.HasPrefix(protocol, "tcp") {
+			entry.state = tcpStates[fields[3]]
+			entry.listen = fields[3] == tcpListen
+		} else {
+			entry.listen = fields[3] == udpUnconnected && port != 0
+		}
+		table[inode] = entry
+	}
+
+	return sc.Err()
+}
+
+// parseNetUnixTable parses /proc/net/unix into the table, keyed by inode
+func parseNetUnixTable(path string, table map[uint64]socketEntry) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("error opening file %s: %w", path, err)
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	// skip the header
+	sc.Scan()
+	for sc.Scan() {
+		// Num RefCount Protocol Flags Type St Inode Path
+		line := sc.Text()
+		fields := strings.Fields(line)
+		if len(fields) < 7 {
+			continue
+		}
+		inode, err := strconv.ParseUint(fields[6], 10, 64)
+		if err != nil || inode == 0 {
+			continue
+		}
+		flags, err := strconv.ParseUint(fields[3], 16, 64)
+		if err != nil {
+			return fmt.Errorf("error parsing flags in %s: %w", path, err)
+		}
+
+		entry := socketEntry{protocol: "unix", listen: flags&unixAcceptCon != 0}
+		if len(fields) > 7 {
+			entry.path = unixSocketPath(line)
+		}
+		table[inode] = entry
+	}
+
+	return sc.Err()
+}
+
+// unixSocketPath returns the path of a /proc/net/unix line, which is the rest of the line after the inode.
+// The path can contain spaces, so it is not split into fields.
+func unixSocketPath(line string) string {
+	rest := line
+	for i := 0; i < 7; i++ {
+		rest = strings.TrimLeft(rest, " ")
+		end := strings.IndexByte(rest, ' ')
+		if end < 0 {
+			return ""
+		}
+		rest = rest[end:]
+	}
+	// the path is separated from the inode by a single space
+	return strings.TrimPrefix(rest, " ")
+}
+
+// parseNetAddress parses an address:port pair from /proc/net/{tcp,udp}{,6}.
+// The address is made up of 32-bit words in host byte order, the port is in network byte order.
+func parseNetAddress(raw string) (string, int, error) {
+	parts := strings.Split(raw, ":")
+	if len(parts) != 2 {
+		return "", 0, fmt.Errorf("invalid address %q", raw)
+	}
+
+	addr, err := hex.DecodeString(parts[0])
+	if err != nil || (len(addr) != net.IPv4len && len(addr) != net.IPv6len) {
+		return "", 0, fmt.Errorf("invalid address %q", raw)
+	}
+	for i := 0; i < len(addr); i += 4 {
+		addr[i], addr[i+1], addr[i+2], addr[i+3] = addr[i+3], addr[i+2], addr[i+1], addr[i]
+	}
+
+	port, err := strconv.ParseUint(parts[1], 16, 16)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid port in %q: %w", raw, err)
+	}
+
+	return net.IP(addr).String(), int(port), nil
+}
@@ -0,0 +1,110 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package process
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-system-metrics/metric/system/resolve"
+)
+
+func TestParseNetAddress(t *testing.T) {
+	tests := []struct {
+		raw  string
+		addr string
+		port int
+	}{
+		{raw: "0100007F:23F0", addr: "127.0.0.1", port: 9200},
+		{raw: "00000000:0016", addr: "0.0.0.0", port: 22},
+		{raw: "00000000000000000000000000000000:1F90", addr: "::", port: 8080},
+		{raw: "0000000000000000FFFF00000100007F:1F90", addr: "127.0.0.1", port: 8080},
+		{raw: "B80D01200000000067452301EFCDAB89:01BB", addr: "2001:db8::123:4567:89ab:cdef", port: 443},
+	}
+	for _, test := range tests {
+		addr, port, err := parseNetAddress(test.raw)
+		require.NoError(t, err, test.raw)
+		assert.Equal(t, test.addr, addr, test.raw)
+		assert.Equal(t, test.port, port, test.raw)
+	}
+
+	_, _, err := parseNetAddress("0100007F")
+	assert.Error(t, err)
+}
+
+func TestSocketTable(t *testing.T) {
+	cache := newSocketCache()
+	table, err := cache.getTable(resolve.NewTestResolver("testdata"), 42, "net:[4026531840]")
+	require.NoError(t, err)
+
+	want := map[uint64]socketEntry{
+		4097383: {protocol: "tcp", address: "127.0.0.1", port: 9200, state: "listen", listen: true},
+		4097390: {protocol: "tcp", address: "127.0.0.1", port: 9200, state: "established"},
+		4097384: {protocol: "tcp6", address: "::", port: 8080, state: "listen", listen: true},
+		4097391: {protocol: "tcp6", address: "127.0.0.1", port: 8080, state: "close_wait"},
+		4097385: {protocol: "udp", address: "0.0.0.0", port: 5353, listen: true},
+		4097386: {protocol: "unix", path: "/run/elastic-agent.sock", listen: true},
+		4097387: {protocol: "unix"},
+		4097388: {protocol: "unix", path: "/tmp/My Service/agent.sock", listen: true},
+	}
+	assert.Equal(t, want, table)
+
+	// the second lookup for the same namespace comes from the cache
+	cached, err := cache.getTable(resolve.NewTestResolver("/does/not/exist"), 42, "net:[4026531840]")
+	require.NoError(t, err)
+	assert.Equal(t, want, cached)
+}
+
+func TestSelfSockets(t *testing.T) {
+	tcp, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer tcp.Close()
+	client, err := net.Dial("tcp", tcp.Addr().String())
+	require.NoError(t, err)
+	defer client.Close()
+	server, err := tcp.Accept()
+	require.NoError(t, err)
+	defer server.Close()
+
+	sockPath := filepath.Join(t.TempDir(), "test.sock")
+	unix, err := net.Listen("unix", sockPath)
+	require.NoError(t, err)
+	defer unix.Close()
+
+	stat := Stats{
+		Procs:         []string{".*"},
+		Hostfs:        resolve.NewTestResolver("/"),
+		EnableSockets: true,
+	}
+	require.NoError(t, stat.Init())
+	self, err := stat.GetSelf()
+	require.NoError(t, err)
+
+	port := tcp.Addr().(*net.TCPAddr).Port
+	assert.Contains(t, self.Socket.Listening, ListeningSocket{Protocol: "tcp", Address: "127.0.0.1", Port: port})
+	assert.Contains(t, self.Socket.Listening, ListeningSocket{Protocol: "unix", Path: sockPath})
+	assert.Equal(t, 2, self.Socket.Connections["established"])
+	assert.Equal(t, os.Getpid(), self.Pid.ValueOr(0))
+}
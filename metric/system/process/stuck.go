@@ -0,0 +1,75 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build darwin || freebsd || linux || windows || aix || netbsd || openbsd
+
+package process
+
+import (
+	"sort"
+)
+
+// StuckProcess is a process that stayed blocked in the DiskSleep or Stopped state
+type StuckProcess struct {
+	Pid   int
+	Name  string
+	State PidState
+	// Samples is the number of consecutive samples the process was found blocked
+	Samples int
+	Channel string
+	Stack   []string
+}
+
+// isStuckState returns true for the states a process can hang in
+func isStuckState(state PidState) bool {
+	return state == DiskSleep || state == Stopped
+}
+
+// FindStuckProcesses returns the processes that were found blocked in at least threshold consecutive samples,
+// with the processes that were blocked the longest first.
+func FindStuckProcesses(procs ProcsMap, threshold int) []StuckProcess {
+	var stuck []StuckProcess
+	for pid, proc := range procs {
+		samples := proc.Wait.StuckSamples.ValueOr(0)
+		if samples == 0 || samples < threshold {
+			continue
+		}
+		stuck = append(stuck, StuckProcess{
+			Pid:     pid,
+			Name:    proc.Name,
+			State:   proc.State,
+			Samples: samples,
+			Channel: proc.Wait.Channel,
+			Stack:   proc.Wait.Stack,
+		})
+	}
+	sort.Slice(stuck, func(i, j int) bool {
+		if stuck[i].Samples != stuck[j].Samples {
+			return stuck[i].Samples > stuck[j].Samples
+		}
+		return stuck[i].Pid < stuck[j].Pid
+	})
+	return stuck
+}
+
+// StuckProcesses returns the processes that were blocked for at least StuckThreshold samples as of the last fetch.
+func (procStats *Stats) StuckProcesses() []StuckProcess {
+	if procStats.StuckThreshold <= 0 {
+		return nil
+	}
+	return FindStuckProcesses(procStats.ProcsMap.Snapshot(), procStats.StuckThreshold)
+}
@@ -0,0 +1,75 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package process
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/elastic-agent-system-metrics/metric/system/resolve"
+)
+
+// getWaitInfo fetches the wait channel of a process from /proc/[PID]/wchan,
+// and its kernel stack from /proc/[PID]/stack. The stack is only readable with CAP_SYS_ADMIN,
+// and is left empty if access is denied.
+func getWaitInfo(hostfs resolve.Resolver, pid int) (string, []string, error) {
+	path := hostfs.Join("proc", strconv.Itoa(pid), "wchan")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("error reading file %s: %w", path, err)
+	}
+	channel := strings.TrimSpace(string(data))
+	// "0" is returned for running processes, and when the address can't be resolved to a symbol
+	if channel == "0" {
+		channel = ""
+	}
+
+	path = hostfs.Join("proc", strconv.Itoa(pid), "stack")
+	data, err = os.ReadFile(path)
+	if errors.Is(err, os.ErrPermission) {
+		return channel, nil, nil
+	} else if err != nil {
+		return channel, nil, fmt.Errorf("error reading file %s: %w", path, err)
+	}
+
+	return channel, parseKernelStack(string(data)), nil
+}
+
+// parseKernelStack strips the addresses from the lines of /proc/[PID]/stack,
+// such as "[<0>] io_schedule+0x46/0x70"
+func parseKernelStack(data string) []string {
+	var stack []string
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[<") {
+			if _, frame, ok := strings.Cut(line, "] "); ok {
+				line = frame
+			}
+		}
+		stack = append(stack, line)
+	}
+	return stack
+}
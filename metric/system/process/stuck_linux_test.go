@@ -0,0 +1,90 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package process
+
+import (
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-system-metrics/metric/system/resolve"
+)
+
+func TestGetWaitInfo(t *testing.T) {
+	channel, stack, err := getWaitInfo(resolve.NewTestResolver("testdata"), 42)
+	require.NoError(t, err)
+	assert.Equal(t, "rpc_wait_bit_killable", channel)
+	assert.Equal(t, []string{
+		"rpc_wait_bit_killable+0x1e/0xa0 [sunrpc]",
+		"__rpc_execute+0x10e/0x3e0 [sunrpc]",
+		"rpc_execute+0x8b/0xc0 [sunrpc]",
+		"nfs4_do_call_sync+0x67/0xa0 [nfsv4]",
+		"do_syscall_64+0x5b/0x80",
+		"entry_SYSCALL_64_after_hwframe+0x72/0xdc",
+	}, stack)
+}
+
+func TestStuckProcesses(t *testing.T) {
+	cmd := exec.Command("sleep", "60")
+	if err := cmd.Start(); err != nil {
+		t.Skipf("could not start sleep: %s", err)
+	}
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+	pid := cmd.Process.Pid
+	require.NoError(t, cmd.Process.Signal(syscall.SIGSTOP))
+	require.Eventually(t, func() bool {
+		state, err := GetPIDState(resolve.NewTestResolver("/"), pid)
+		return err == nil && state == Stopped
+	}, 5*time.Second, 10*time.Millisecond)
+
+	stat, err := initTestResolver()
+	require.NoError(t, err)
+	stat.StuckThreshold = 2
+	require.NoError(t, stat.Init())
+
+	_, err = stat.GetOne(pid)
+	require.NoError(t, err)
+	assert.Empty(t, stat.StuckProcesses())
+
+	_, err = stat.GetOne(pid)
+	require.NoError(t, err)
+	stuck := stat.StuckProcesses()
+	require.Len(t, stuck, 1)
+	assert.Equal(t, pid, stuck[0].Pid)
+	assert.Equal(t, Stopped, stuck[0].State)
+	assert.Equal(t, 2, stuck[0].Samples)
+
+	// the count starts over once the process runs again
+	require.NoError(t, cmd.Process.Signal(syscall.SIGCONT))
+	require.Eventually(t, func() bool {
+		state, err := GetPIDState(resolve.NewTestResolver("/"), pid)
+		return err == nil && state != Stopped
+	}, 5*time.Second, 10*time.Millisecond)
+	_, err = stat.GetOne(pid)
+	require.NoError(t, err)
+	assert.Empty(t, stat.StuckProcesses())
+}
@@ -0,0 +1,45 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build darwin || freebsd || linux || windows
+
+package process
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/elastic-agent-libs/opt"
+)
+
+func TestFindStuckProcesses(t *testing.T) {
+	procs := ProcsMap{
+		10: {Name: "nfsd", State: DiskSleep, Wait: ProcWaitInfo{StuckSamples: opt.IntWith(3), Channel: "rpc_wait_bit_killable"}},
+		20: {Name: "rsync", State: DiskSleep, Wait: ProcWaitInfo{StuckSamples: opt.IntWith(7)}},
+		30: {Name: "vim", State: Stopped, Wait: ProcWaitInfo{StuckSamples: opt.IntWith(3)}},
+		40: {Name: "cp", State: DiskSleep, Wait: ProcWaitInfo{StuckSamples: opt.IntWith(1)}},
+		50: {Name: "bash", State: Sleeping},
+	}
+
+	assert.Equal(t, []StuckProcess{
+		{Pid: 20, Name: "rsync", State: DiskSleep, Samples: 7},
+		{Pid: 10, Name: "nfsd", State: DiskSleep, Samples: 3, Channel: "rpc_wait_bit_killable"},
+		{Pid: 30, Name: "vim", State: Stopped, Samples: 3},
+	}, FindStuckProcesses(procs, 2))
+	assert.Len(t, FindStuckProcesses(procs, 0), 4)
+}
@@ -0,0 +1,102 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package process
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/elastic/elastic-agent-libs/opt"
+	"github.com/elastic/elastic-agent-system-metrics/metric/system/resolve"
+	"github.com/elastic/elastic-agent-system-metrics/metric/system/sysconf"
+)
+
+// getThreads fetches per-thread metrics for every thread listed in /proc/[PID]/task
+func getThreads(hostfs resolve.Resolver, pid int) ([]ThreadState, error) {
+	pidStr := strconv.Itoa(pid)
+	tasks, err := os.ReadDir(hostfs.Join("proc", pidStr, "task"))
+	if err != nil {
+		return nil, fmt.Errorf("error reading task directory for pid %d: %w", pid, err)
+	}
+
+	threads := make([]ThreadState, 0, len(tasks))
+	for _, task := range tasks {
+		if !dirIsPid(task.Name()) {
+			continue
+		}
+		tid, err := strconv.Atoi(task.Name())
+		if err != nil {
+			continue
+		}
+
+		path := hostfs.Join("proc", pidStr, "task", task.Name(), "stat")
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) { // the thread exited after we listed the directory
+			continue
+		} else if err != nil {
+			return threads, fmt.Errorf("error opening file %s: %w", path, err)
+		}
+
+		thread, err := parseThreadStat(data, sysconf.ClockTicks(hostfs))
+		if err != nil {
+			return threads, fmt.Errorf("failed to parse information for tid %d: %w", tid, err)
+		}
+		thread.Tid = opt.IntWith(tid)
+		threads = append(threads, thread)
+	}
+
+	return threads, nil
+}
+
+// parseThreadStat parses the contents of /proc/[PID]/task/[TID]/stat, with CPU times in clockTicks per second
+func parseThreadStat(data []byte, clockTicks uint64) (ThreadState, error) {
+	thread := ThreadState{}
+
+	comm, fields, err := splitProcStat(data)
+	thread.Name = comm
+	if err != nil {
+		return thread, err
+	}
+	thread.State = getProcState(fields[0][0])
+
+	// See https://man7.org/linux/man-pages/man5/proc.5.html for all fields.
+	user, err := strconv.ParseUint(string(fields[11]), 10, 64)
+	if err != nil {
+		return thread, fmt.Errorf("error parsing user CPU times: %w", err)
+	}
+	sys, err := strconv.ParseUint(string(fields[12]), 10, 64)
+	if err != nil {
+		return thread, fmt.Errorf("error parsing system CPU times: %w", err)
+	}
+	processor, err := strconv.Atoi(string(fields[36]))
+	if err != nil {
+		return thread, fmt.Errorf("error parsing processor: %w", err)
+	}
+
+	// ticks are converted to milliseconds, same as getCPUTime
+	thread.CPU.User.Ticks = opt.UintWith(user * 1000 / clockTicks)
+	thread.CPU.System.Ticks = opt.UintWith(sys * 1000 / clockTicks)
+	thread.CPU.Total.Ticks = opt.UintWith(opt.SumOptUint(thread.CPU.User.Ticks, thread.CPU.System.Ticks))
+	thread.Processor = opt.IntWith(processor)
+
+	return thread, nil
+}
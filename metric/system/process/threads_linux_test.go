@@ -0,0 +1,105 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package process
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-libs/opt"
+	"github.com/elastic/elastic-agent-system-metrics/metric/system/resolve"
+)
+
+func TestGetThreads(t *testing.T) {
+	threads, err := getThreads(resolve.NewTestResolver("testdata"), 42)
+	require.NoError(t, err)
+
+	want := []ThreadState{
+		{
+			Tid:       opt.IntWith(42),
+			Name:      "elastic-agent",
+			State:     Sleeping,
+			Processor: opt.IntWith(3),
+			CPU: ProcCPUInfo{
+				User:   CPUTicks{Ticks: opt.UintWith(50000)},
+				System: CPUTicks{Ticks: opt.UintWith(20000)},
+				Total:  CPUTotal{Ticks: opt.UintWith(70000)},
+			},
+		},
+		{
+			Tid:       opt.IntWith(43),
+			Name:      "GC Worker 1",
+			State:     Running,
+			Processor: opt.IntWith(5),
+			CPU: ProcCPUInfo{
+				User:   CPUTicks{Ticks: opt.UintWith(32290)},
+				System: CPUTicks{Ticks: opt.UintWith(19890)},
+				Total:  CPUTotal{Ticks: opt.UintWith(52180)},
+			},
+		},
+	}
+	assert.Equal(t, want, threads)
+}
+
+func TestThreadCPUPercentage(t *testing.T) {
+	thread := func(tid int, ticks uint64) ThreadState {
+		return ThreadState{Tid: opt.IntWith(tid), CPU: ProcCPUInfo{Total: CPUTotal{Ticks: opt.UintWith(ticks)}}}
+	}
+	s0 := ProcState{
+		Threads:    []ThreadState{thread(1, 1000), thread(2, 500)},
+		SampleTime: time.Now(),
+	}
+	s1 := ProcState{
+		Threads:    []ThreadState{thread(1, 1500), thread(2, 500), thread(3, 100)},
+		SampleTime: s0.SampleTime.Add(time.Second),
+	}
+
+	s1 = GetThreadCPUPercentage(s0, s1)
+	assert.Equal(t, 0.5, s1.Threads[0].CPU.Total.Pct.ValueOr(-1))
+	assert.Equal(t, float64(0), s1.Threads[1].CPU.Total.Pct.ValueOr(-1))
+	// new thread, no previous sample
+	assert.False(t, s1.Threads[2].CPU.Total.Pct.Exists())
+}
+
+func TestSelfThreads(t *testing.T) {
+	stat, err := initTestResolver()
+	require.NoError(t, err)
+	stat.EnableThreads = true
+
+	_, err = stat.GetSelf()
+	require.NoError(t, err)
+	time.Sleep(time.Millisecond * 5)
+	self, err := stat.GetSelf()
+	require.NoError(t, err)
+
+	require.NotEmpty(t, self.Threads)
+	tids := []int{}
+	for _, thread := range self.Threads {
+		tids = append(tids, thread.Tid.ValueOr(0))
+		assert.NotEmpty(t, thread.Name)
+		assert.NotEmpty(t, thread.State)
+		assert.True(t, thread.Processor.Exists())
+	}
+	assert.Contains(t, tids, os.Getpid())
+}
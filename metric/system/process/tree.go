@@ -0,0 +1,158 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build darwin || freebsd || linux || windows || aix || netbsd || openbsd
+
+package process
+
+import (
+	"sort"
+
+	"github.com/elastic/elastic-agent-system-metrics/metric"
+)
+
+// Tree is a parent/child index of a ProcsMap snapshot.
+// It is not updated when the underlying ProcsMap changes; build a new one for each snapshot.
+type Tree struct {
+	procs    ProcsMap
+	children map[int][]int
+}
+
+// TreeRollup contains resource metrics summed over a process and all of its descendants.
+type TreeRollup struct {
+	Processes  int
+	NumThreads int
+	CPUPct     float64
+	CPUNormPct float64
+	RssBytes   uint64
+	FDOpen     uint64
+}
+
+// NewTree builds a process tree from the given ProcsMap.
+// Processes whose parent is not in the map are treated as roots.
+func NewTree(procs ProcsMap) *Tree {
+	tree := &Tree{
+		procs:    procs,
+		children: make(map[int][]int, len(procs)),
+	}
+	for pid, proc := range procs {
+		ppid := proc.Ppid.ValueOr(pid)
+		if ppid == pid {
+			continue
+		}
+		tree.children[ppid] = append(tree.children[ppid], pid)
+	}
+	for ppid := range tree.children {
+		sort.Ints(tree.children[ppid])
+	}
+	return tree
+}
+
+// Tree returns a process tree built from the processes tracked during the last fetch.
+func (procStats *Stats) Tree() *Tree {
+	return NewTree(procStats.ProcsMap.Snapshot())
+}
+
+// Get returns the process state for the given PID.
+func (t *Tree) Get(pid int) (ProcState, bool) {
+	proc, ok := t.procs[pid]
+	return proc, ok
+}
+
+// Roots returns the PIDs of all processes whose parent is not part of the tree.
+func (t *Tree) Roots() []int {
+	var roots []int
+	for pid, proc := range t.procs {
+		ppid := proc.Ppid.ValueOr(pid)
+		if _, ok := t.procs[ppid]; !ok || ppid == pid {
+			roots = append(roots, pid)
+		}
+	}
+	sort.Ints(roots)
+	return roots
+}
+
+// Children returns the PIDs of the direct children of pid.
+func (t *Tree) Children(pid int) []int {
+	return append([]int(nil), t.children[pid]...)
+}
+
+// Descendants returns the PIDs of all processes below pid, in breadth-first order.
+func (t *Tree) Descendants(pid int) []int {
+	var found []int
+	seen := map[int]struct{}{pid: {}}
+	queue := t.Children(pid)
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		// guard against loops from PIDs that were reused mid-scan
+		if _, ok := seen[cur]; ok {
+			continue
+		}
+		seen[cur] = struct{}{}
+		found = append(found, cur)
+		queue = append(queue, t.children[cur]...)
+	}
+	return found
+}
+
+// Ancestors returns the PIDs of the parent chain of pid, starting with the direct parent.
+// The walk stops at the first parent that is not part of the tree.
+func (t *Tree) Ancestors(pid int) []int {
+	var found []int
+	seen := map[int]struct{}{pid: {}}
+	cur := pid
+	for {
+		proc, ok := t.procs[cur]
+		if !ok {
+			return found
+		}
+		ppid := proc.Ppid.ValueOr(cur)
+		if _, ok := seen[ppid]; ok {
+			return found
+		}
+		if _, ok := t.procs[ppid]; !ok {
+			return found
+		}
+		seen[ppid] = struct{}{}
+		found = append(found, ppid)
+		cur = ppid
+	}
+}
+
+// Rollup sums the resource metrics of pid and all of its descendants.
+// The second return value is false if pid is not part of the tree.
+func (t *Tree) Rollup(pid int) (TreeRollup, bool) {
+	if _, ok := t.procs[pid]; !ok {
+		return TreeRollup{}, false
+	}
+
+	rollup := TreeRollup{}
+	for _, member := range append([]int{pid}, t.Descendants(pid)...) {
+		proc := t.procs[member]
+		rollup.Processes++
+		rollup.NumThreads += proc.NumThreads.ValueOr(0)
+		rollup.CPUPct += proc.CPU.Total.Pct.ValueOr(0)
+		rollup.CPUNormPct += proc.CPU.Total.Norm.Pct.ValueOr(0)
+		rollup.RssBytes += proc.Memory.Rss.Bytes.ValueOr(0)
+		rollup.FDOpen += proc.FD.Open.ValueOr(0)
+	}
+	rollup.CPUPct = metric.Round(rollup.CPUPct)
+	rollup.CPUNormPct = metric.Round(rollup.CPUNormPct)
+
+	return rollup, true
+}
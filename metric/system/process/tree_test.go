@@ -0,0 +1,140 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build darwin || freebsd || linux || windows
+
+package process
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-libs/opt"
+)
+
+// testProc describes a process for tests that work on a table of processes.
+// Fields left at their zero value are not set on the built ProcState.
+type testProc struct {
+	pid, ppid, session int
+	name               string
+	start, sample      time.Time
+	threads            int
+	cpuPct             float64
+	rss, fds           uint64
+}
+
+func (p testProc) state() ProcState {
+	state := ProcState{
+		Name:       p.name,
+		Pid:        opt.IntWith(p.pid),
+		SampleTime: p.sample,
+	}
+	if p.ppid != 0 {
+		state.Ppid = opt.IntWith(p.ppid)
+	}
+	if p.session != 0 {
+		state.Session = opt.IntWith(p.session)
+	}
+	if !p.start.IsZero() {
+		state.CPU.StartTime = unixTimeMsToTime(uint64(p.start.UnixMilli()))
+	}
+	if p.threads != 0 {
+		state.NumThreads = opt.IntWith(p.threads)
+	}
+	if p.cpuPct != 0 {
+		state.CPU.Total.Pct = opt.FloatWith(p.cpuPct)
+	}
+	if p.rss != 0 {
+		state.Memory.Rss.Bytes = opt.UintWith(p.rss)
+	}
+	if p.fds != 0 {
+		state.FD.Open = opt.UintWith(p.fds)
+	}
+	return state
+}
+
+func TestTree(t *testing.T) {
+	// 1
+	// ├── 10
+	// │   ├── 11
+	// │   │   └── 13
+	// │   └── 12
+	// └── 20
+	// 30 (parent 5 not tracked)
+	procs := ProcsMap{
+		1:  testProc{pid: 1, threads: 2, cpuPct: 0.1, rss: 100, fds: 10}.state(),
+		10: testProc{pid: 10, ppid: 1, threads: 2, cpuPct: 1.5, rss: 1000, fds: 10}.state(),
+		11: testProc{pid: 11, ppid: 10, threads: 2, cpuPct: 0.25, rss: 200, fds: 10}.state(),
+		12: testProc{pid: 12, ppid: 10, threads: 2, cpuPct: 0.25, rss: 300, fds: 10}.state(),
+		13: testProc{pid: 13, ppid: 11, threads: 2, cpuPct: 1, rss: 400, fds: 10}.state(),
+		20: testProc{pid: 20, ppid: 1, threads: 2, cpuPct: 2, rss: 500, fds: 10}.state(),
+		30: testProc{pid: 30, ppid: 5, threads: 2, cpuPct: 3, rss: 600, fds: 10}.state(),
+	}
+	tree := NewTree(procs)
+
+	assert.Equal(t, []int{1, 30}, tree.Roots())
+	assert.Equal(t, []int{10, 20}, tree.Children(1))
+	assert.Empty(t, tree.Children(13))
+	assert.Equal(t, []int{11, 12, 13}, tree.Descendants(10))
+	assert.Equal(t, []int{11, 10, 1}, tree.Ancestors(13))
+	assert.Empty(t, tree.Ancestors(30))
+
+	rollup, ok := tree.Rollup(10)
+	require.True(t, ok)
+	assert.Equal(t, TreeRollup{
+		Processes:  4,
+		NumThreads: 8,
+		CPUPct:     3,
+		RssBytes:   1900,
+		FDOpen:     40,
+	}, rollup)
+
+	_, ok = tree.Rollup(99)
+	assert.False(t, ok)
+}
+
+func TestTreeLoop(t *testing.T) {
+	// A reused PID can briefly make two processes each other's parent.
+	procs := ProcsMap{
+		2: testProc{pid: 2, ppid: 3, threads: 2, fds: 10}.state(),
+		3: testProc{pid: 3, ppid: 2, threads: 2, fds: 10}.state(),
+	}
+	tree := NewTree(procs)
+
+	assert.Equal(t, []int{3}, tree.Ancestors(2))
+	assert.Equal(t, []int{3}, tree.Descendants(2))
+}
+
+func TestStatsTree(t *testing.T) {
+	stat, err := initTestResolver()
+	require.NoError(t, err)
+	_, _, err = stat.Get()
+	require.NoError(t, err)
+
+	tree := stat.Tree()
+	self, ok := tree.Get(os.Getpid())
+	require.True(t, ok, "own process not found in tree")
+
+	rollup, ok := tree.Rollup(self.Pid.ValueOr(0))
+	require.True(t, ok)
+	assert.GreaterOrEqual(t, rollup.Processes, 1)
+	assert.NotZero(t, rollup.RssBytes)
+}
@@ -0,0 +1,139 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build freebsd || linux
+
+package process
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elastic/elastic-agent-system-metrics/metric/system/resolve"
+)
+
+// idFiles caches the parsed passwd and group files by path, so that several hostfs roots can be used at once.
+var idFiles = struct {
+	sync.Mutex
+	files map[string]idFile
+}{files: map[string]idFile{}}
+
+type idFile struct {
+	modTime time.Time
+	size    int64
+	names   map[string]string
+}
+
+// userDB resolves user and group IDs to names from the passwd and group files under the hostfs root,
+// rather than from the databases of the system we are running on.
+type userDB struct {
+	hostfs resolve.Resolver
+	users  map[string]string
+	groups map[string]string
+}
+
+// newUserDB returns the user and group names under the hostfs root.
+// The files are only parsed again if they changed since the last call.
+func newUserDB(hostfs resolve.Resolver) userDB {
+	// a missing or unreadable file is not an error, we fall back to numeric IDs
+	users, _ := loadIDFile(hostfs.Join("etc", "passwd"))
+	groups, _ := loadIDFile(hostfs.Join("etc", "group"))
+	return userDB{hostfs: hostfs, users: users, groups: groups}
+}
+
+// userName returns the name of a user ID, or an empty string if it is unknown.
+func (db userDB) userName(uid string) string {
+	if name, ok := db.users[uid]; ok {
+		return name
+	}
+	// os/user can also find users from other sources, such as LDAP, but only those of our own root filesystem
+	if !db.hostfs.IsSet() {
+		if u, err := user.LookupId(uid); err == nil {
+			return u.Username
+		}
+	}
+	return ""
+}
+
+// groupName returns the name of a group ID, or an empty string if it is unknown.
+func (db userDB) groupName(gid string) string {
+	if name, ok := db.groups[gid]; ok {
+		return name
+	}
+	if !db.hostfs.IsSet() {
+		if g, err := user.LookupGroupId(gid); err == nil {
+			return g.Name
+		}
+	}
+	return ""
+}
+
+// loadIDFile returns the ID to name mapping of a passwd or group file, from the cache if the file is unchanged.
+func loadIDFile(path string) (map[string]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	idFiles.Lock()
+	defer idFiles.Unlock()
+	cached, ok := idFiles.files[path]
+	if ok && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		return cached.names, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	names, err := parseIDFile(f)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", path, err)
+	}
+	idFiles.files[path] = idFile{modTime: info.ModTime(), size: info.Size(), names: names}
+
+	return names, nil
+}
+
+// parseIDFile parses a passwd or group file into a map of ID to name.
+// Both formats start with name:password:ID. The first entry for an ID wins, same as for getpwuid(3).
+func parseIDFile(r io.Reader) (map[string]string, error) {
+	names := map[string]string{}
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := sc.Text()
+		// skip comments and NIS compat entries
+		if line == "" || line[0] == '#' || line[0] == '+' || line[0] == '-' {
+			continue
+		}
+		fields := strings.SplitN(line, ":", 4)
+		if len(fields) < 3 {
+			continue
+		}
+		if _, ok := names[fields[2]]; !ok {
+			names[fields[2]] = fields[0]
+		}
+	}
+	return names, sc.Err()
+}
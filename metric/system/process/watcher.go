@@ -0,0 +1,240 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build (darwin && cgo) || freebsd || linux || windows || aix
+
+package process
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// WatchFilter selects the processes delivered to a subscriber. A nil filter selects all processes.
+type WatchFilter func(ProcState) bool
+
+// Snapshot is delivered to a subscriber of a Watcher once per interval.
+type Snapshot struct {
+	Time time.Time
+	// Processes are the processes selected by the filter of the subscriber.
+	// The states are shared between subscribers and must not be modified.
+	Processes ProcsMap
+	// Events are the processes that were started or exited since the last snapshot
+	// delivered to the subscriber, and that are selected by the filter. A process that keeps
+	// running while it stops or starts matching the filter is not reported.
+	// No events are reported in the first snapshot.
+	Events []LifecycleEvent
+	// Err is set when the scan failed, in which case no processes are reported.
+	Err error
+}
+
+// Watcher delivers process snapshots to multiple subscribers, sharing a single scan
+// of the host between them.
+type Watcher struct {
+	stats *Stats
+	scan  func() (ProcsMap, error)
+
+	mut     sync.Mutex
+	subs    map[*subscription]struct{}
+	running bool
+	wake    chan struct{}
+}
+
+type subscription struct {
+	interval time.Duration
+	filter   WatchFilter
+	next     time.Time
+	// prev is the unfiltered scan of the last delivered snapshot
+	prev    ProcsMap
+	hasPrev bool
+	ch      chan Snapshot
+}
+
+// NewWatcher returns a Watcher that scans processes with the given Stats, which must be initialized.
+// The Procs regexes and other settings of the Stats apply to all subscribers, and the Stats
+// must not be used by anything else while the Watcher has subscribers.
+func NewWatcher(stats *Stats) *Watcher {
+	watcher := &Watcher{
+		stats: stats,
+		subs:  map[*subscription]struct{}{},
+		wake:  make(chan struct{}, 1),
+	}
+	watcher.scan = watcher.fetch
+	return watcher
+}
+
+// Watch subscribes to process snapshots selected by filter every interval, starting immediately.
+// The returned channel is closed when the context is cancelled. Snapshots are dropped if the
+// subscriber is not ready to receive them, lifecycle events are then reported with the next
+// snapshot that is delivered.
+// Subscribers that are due at the same time share the same scan, so the host is scanned at
+// most as often as the shortest interval requires.
+func (w *Watcher) Watch(ctx context.Context, interval time.Duration, filter WatchFilter) (<-chan Snapshot, error) {
+	if interval <= 0 {
+		return nil, errors.New("watch interval must be positive")
+	}
+	sub := &subscription{
+		interval: interval,
+		filter:   filter,
+		next:     time.Now(),
+		ch:       make(chan Snapshot, 1),
+	}
+
+	w.mut.Lock()
+	w.subs[sub] = struct{}{}
+	if !w.running {
+		w.running = true
+		go w.run()
+	}
+	w.mut.Unlock()
+	w.notify()
+
+	go func() {
+		<-ctx.Done()
+		w.mut.Lock()
+		delete(w.subs, sub)
+		close(sub.ch)
+		w.mut.Unlock()
+		w.notify()
+	}()
+
+	return sub.ch, nil
+}
+
+// notify wakes up the scan loop so it picks up changes to the subscribers
+func (w *Watcher) notify() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run scans the processes whenever a subscriber is due, until there are no subscribers left
+func (w *Watcher) run() {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		w.mut.Lock()
+		if len(w.subs) == 0 {
+			w.running = false
+			w.mut.Unlock()
+			return
+		}
+		var next time.Time
+		for sub := range w.subs {
+			if next.IsZero() || sub.next.Before(next) {
+				next = sub.next
+			}
+		}
+		w.mut.Unlock()
+
+		if wait := time.Until(next); wait > 0 {
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			timer.Reset(wait)
+			select {
+			case <-timer.C:
+			case <-w.wake:
+				continue
+			}
+		}
+
+		procs, err := w.scan()
+		now := time.Now()
+
+		w.mut.Lock()
+		var due []*subscription
+		for sub := range w.subs {
+			// deliver to every subscriber that is close to being due, so they keep sharing scans
+			if sub.next.Sub(now) > sub.interval/10 {
+				continue
+			}
+			sub.next = now.Add(sub.interval)
+			due = append(due, sub)
+		}
+		w.mut.Unlock()
+
+		// the filters run without the lock, so a slow filter doesn't block Watch or cancellation
+		for _, sub := range due {
+			snap := sub.snapshot(now, procs, err)
+			w.mut.Lock()
+			// the channel is closed once the subscriber is removed
+			if _, ok := w.subs[sub]; ok {
+				sub.deliver(snap, procs)
+			}
+			w.mut.Unlock()
+		}
+	}
+}
+
+// fetch scans the processes with the Stats of the Watcher
+func (w *Watcher) fetch() (ProcsMap, error) {
+	procs, _, err := w.stats.fetch()
+	if err != nil {
+		return nil, err
+	}
+	// the map is tracked by the Stats, so the percentages are set on a copy
+	totalPhyMem := w.stats.totalMemory()
+	snapshot := make(ProcsMap, len(procs))
+	for pid, proc := range procs {
+		proc.Memory.Rss.Pct = GetProcMemPercentage(proc, totalPhyMem)
+		snapshot[pid] = proc
+	}
+	return snapshot, nil
+}
+
+// snapshot selects the processes and lifecycle events of a scan with the filter of the subscriber
+func (sub *subscription) snapshot(now time.Time, procs ProcsMap, err error) Snapshot {
+	snap := Snapshot{Time: now, Err: err}
+	if err == nil {
+		snap.Processes = make(ProcsMap)
+		for pid, proc := range procs {
+			if sub.filter == nil || sub.filter(proc) {
+				snap.Processes[pid] = proc
+			}
+		}
+		// processes that stop or start matching the filter while they keep running must not be
+		// reported as exited or started, so the unfiltered scans are compared
+		if sub.hasPrev {
+			for _, event := range DiffProcsMaps(sub.prev, procs) {
+				if sub.filter == nil || sub.filter(event.Process) {
+					snap.Events = append(snap.Events, event)
+				}
+			}
+		}
+	}
+	return snap
+}
+
+// deliver sends a snapshot to the subscriber without blocking
+func (sub *subscription) deliver(snap Snapshot, procs ProcsMap) {
+	select {
+	case sub.ch <- snap:
+	default:
+		return
+	}
+	if snap.Err == nil {
+		sub.prev, sub.hasPrev = procs, true
+	}
+}
@@ -0,0 +1,255 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build darwin || freebsd || linux || windows
+
+package process
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func receiveSnapshot(t *testing.T, ch <-chan Snapshot) Snapshot {
+	t.Helper()
+	select {
+	case snap, ok := <-ch:
+		require.True(t, ok, "channel closed")
+		return snap
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "timed out waiting for snapshot")
+	}
+	return Snapshot{}
+}
+
+func sortedPids(procs ProcsMap) []int {
+	pids := make([]int, 0, len(procs))
+	for pid := range procs {
+		pids = append(pids, pid)
+	}
+	sort.Ints(pids)
+	return pids
+}
+
+func TestWatcherSharedScan(t *testing.T) {
+	start := time.Now().Add(-time.Hour)
+
+	var mut sync.Mutex
+	scans := 0
+	// pid 3 exits and pid 4 starts once exited is set
+	exited := false
+	watcher := NewWatcher(nil)
+	watcher.scan = func() (ProcsMap, error) {
+		mut.Lock()
+		defer mut.Unlock()
+		scans++
+		procs := ProcsMap{
+			1: testProc{pid: 1, start: start, sample: time.Now()}.state(),
+			2: testProc{pid: 2, start: start, sample: time.Now()}.state(),
+		}
+		if exited {
+			procs[4] = testProc{pid: 4, start: start, sample: time.Now()}.state()
+		} else {
+			procs[3] = testProc{pid: 3, start: start, sample: time.Now()}.state()
+		}
+		return procs, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	all, err := watcher.Watch(ctx, 100*time.Millisecond, nil)
+	require.NoError(t, err)
+	odd, err := watcher.Watch(ctx, 100*time.Millisecond, func(proc ProcState) bool {
+		return proc.Pid.ValueOr(0)%2 == 1
+	})
+	require.NoError(t, err)
+
+	first := receiveSnapshot(t, all)
+	require.NoError(t, first.Err)
+	assert.Len(t, first.Processes, 3)
+	assert.Empty(t, first.Events)
+
+	firstOdd := receiveSnapshot(t, odd)
+	assert.Equal(t, []int{1, 3}, sortedPids(firstOdd.Processes))
+	assert.Empty(t, firstOdd.Events)
+
+	mut.Lock()
+	exited = true
+	mut.Unlock()
+
+	deliveries := 2
+	for _, ch := range []<-chan Snapshot{all, odd} {
+		snap := receiveSnapshot(t, ch)
+		deliveries++
+		for snap.Processes[3].Pid.Exists() {
+			assert.Empty(t, snap.Events)
+			snap = receiveSnapshot(t, ch)
+			deliveries++
+		}
+		require.NotEmpty(t, snap.Events)
+		assert.Equal(t, ProcessExited, snap.Events[0].Type)
+		assert.Equal(t, 3, snap.Events[0].Pid)
+	}
+
+	for i := 0; i < 3; i++ {
+		snap := receiveSnapshot(t, all)
+		assert.Equal(t, []int{1, 2, 4}, sortedPids(snap.Processes))
+		assert.Empty(t, snap.Events)
+		snapOdd := receiveSnapshot(t, odd)
+		assert.Equal(t, []int{1}, sortedPids(snapOdd.Processes))
+		assert.Empty(t, snapOdd.Events)
+		deliveries += 2
+	}
+
+	mut.Lock()
+	defer mut.Unlock()
+	assert.Less(t, scans, deliveries, "subscribers did not share scans")
+}
+
+func TestWatcherFilterChange(t *testing.T) {
+	start := time.Now().Add(-time.Hour)
+
+	var mut sync.Mutex
+	// pid 2 keeps running, but stops matching the filter once renamed is set
+	renamed := false
+	watcher := NewWatcher(nil)
+	watcher.scan = func() (ProcsMap, error) {
+		mut.Lock()
+		defer mut.Unlock()
+		procs := ProcsMap{}
+		for pid := 1; pid <= 2; pid++ {
+			proc := testProc{pid: pid, name: "worker", start: start, sample: time.Now()}.state()
+			if pid == 2 && renamed {
+				proc.Name = "idle"
+			}
+			procs[pid] = proc
+		}
+		return procs, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch, err := watcher.Watch(ctx, 50*time.Millisecond, func(proc ProcState) bool {
+		return proc.Name == "worker"
+	})
+	require.NoError(t, err)
+
+	first := receiveSnapshot(t, ch)
+	assert.Equal(t, []int{1, 2}, sortedPids(first.Processes))
+
+	mut.Lock()
+	renamed = true
+	mut.Unlock()
+
+	snap := receiveSnapshot(t, ch)
+	for snap.Processes[2].Pid.Exists() {
+		assert.Empty(t, snap.Events)
+		snap = receiveSnapshot(t, ch)
+	}
+	assert.Equal(t, []int{1}, sortedPids(snap.Processes))
+	assert.Empty(t, snap.Events, "a running process that stopped matching the filter was reported")
+}
+
+func TestWatcherScanError(t *testing.T) {
+	watcher := NewWatcher(nil)
+	watcher.scan = func() (ProcsMap, error) {
+		return nil, errors.New("scan failed")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := watcher.Watch(ctx, time.Minute, nil)
+	require.NoError(t, err)
+
+	snap := receiveSnapshot(t, ch)
+	assert.Error(t, snap.Err)
+	assert.Empty(t, snap.Processes)
+
+	cancel()
+	select {
+	case _, ok := <-ch:
+		assert.False(t, ok, "channel not closed")
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "timed out waiting for channel to be closed")
+	}
+
+	_, err = watcher.Watch(context.Background(), 0, nil)
+	assert.Error(t, err)
+}
+
+func TestWatcherSlowFilter(t *testing.T) {
+	watcher := NewWatcher(nil)
+	watcher.scan = func() (ProcsMap, error) {
+		return ProcsMap{1: testProc{pid: 1, start: time.Now(), sample: time.Now()}.state()}, nil
+	}
+
+	filtering := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := watcher.Watch(ctx, time.Minute, func(ProcState) bool {
+		close(filtering)
+		<-release
+		return true
+	})
+	require.NoError(t, err)
+
+	// a blocked filter must not keep the subscription from being cancelled
+	<-filtering
+	cancel()
+	select {
+	case _, ok := <-ch:
+		assert.False(t, ok, "channel not closed")
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "timed out waiting for channel to be closed")
+	}
+}
+
+func TestWatcherSelf(t *testing.T) {
+	stat, err := initTestResolver()
+	require.NoError(t, err)
+	watcher := NewWatcher(&stat)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	self := os.Getpid()
+	ch, err := watcher.Watch(ctx, time.Second, func(proc ProcState) bool {
+		return proc.Pid.ValueOr(0) == self
+	})
+	require.NoError(t, err)
+
+	snap := receiveSnapshot(t, ch)
+	require.NoError(t, snap.Err)
+	require.Len(t, snap.Processes, 1)
+	assert.Equal(t, self, snap.Processes[self].Pid.ValueOr(0))
+	assert.True(t, snap.Processes[self].Memory.Rss.Pct.Exists())
+
+	// the percentages of the subscribers are not stored in the processes tracked by the Stats
+	tracked, ok := stat.ProcsMap.GetPid(self)
+	require.True(t, ok)
+	assert.False(t, tracked.Memory.Rss.Pct.Exists())
+}
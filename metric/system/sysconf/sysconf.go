@@ -0,0 +1,78 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package sysconf detects the memory page size and the clock tick rate of a host,
+// which are needed to convert the values reported by procfs into bytes and seconds.
+package sysconf
+
+import (
+	"os"
+	"sync"
+
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-system-metrics/metric/system/resolve"
+)
+
+// defaultClockTicks is USER_HZ on all common architectures
+const defaultClockTicks = 100
+
+// values holds the detected values of a host, unset values are zero
+type values struct {
+	pageSize   uint64
+	clockTicks uint64
+}
+
+// The values can't change while the system is running, so they are detected once per hostfs.
+var (
+	cacheMut sync.Mutex
+	cache    = map[string]values{}
+)
+
+// PageSize returns the size of a memory page in bytes, as used by /proc/[PID]/statm.
+// If it can't be detected it falls back to the page size seen by the current process.
+func PageSize(hostfs resolve.Resolver) uint64 {
+	return get(hostfs).pageSize
+}
+
+// ClockTicks returns the number of clock ticks per second (USER_HZ), the unit of the CPU times
+// in /proc/[PID]/stat and of the cgroup v1 cpuacct.stat file.
+// If it can't be detected it falls back to 100.
+func ClockTicks(hostfs resolve.Resolver) uint64 {
+	return get(hostfs).clockTicks
+}
+
+func get(hostfs resolve.Resolver) values {
+	key := hostfs.ResolveHostFS("/")
+	cacheMut.Lock()
+	defer cacheMut.Unlock()
+	if cached, ok := cache[key]; ok {
+		return cached
+	}
+
+	detected, err := detect(hostfs)
+	if err != nil {
+		logp.L().Debugf("Error detecting page size and clock ticks, using defaults: %s", err)
+	}
+	if detected.pageSize == 0 {
+		detected.pageSize = uint64(os.Getpagesize())
+	}
+	if detected.clockTicks == 0 {
+		detected.clockTicks = defaultClockTicks
+	}
+	cache[key] = detected
+	return detected
+}
@@ -0,0 +1,112 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package sysconf
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/cpu"
+
+	"github.com/elastic/elastic-agent-system-metrics/metric/system/resolve"
+)
+
+// Auxiliary vector entry types, see include/uapi/linux/auxvec.h
+const (
+	atNull   = 0
+	atPageSz = 6
+	atClkTck = 17
+)
+
+// detect reads the page size and clock ticks from the auxiliary vector the kernel passed to the current process,
+// and falls back to the page size of its first mapping.
+// The procfs of hostfs is used, so that the values describe the kernel hostfs points to.
+func detect(hostfs resolve.Resolver) (values, error) {
+	path := hostfs.Join("proc", "self", "auxv")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return values{}, fmt.Errorf("error reading file %s: %w", path, err)
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if cpu.IsBigEndian {
+		order = binary.BigEndian
+	}
+	detected := parseAuxv(data, strconv.IntSize/8, order)
+	if detected.pageSize != 0 {
+		return detected, nil
+	}
+
+	detected.pageSize, err = smapsPageSize(hostfs.Join("proc", "self", "smaps"))
+	return detected, err
+}
+
+// parseAuxv parses the pairs of native words in /proc/[PID]/auxv, see getauxval(3)
+func parseAuxv(data []byte, wordSize int, order binary.ByteOrder) values {
+	word := func(b []byte) uint64 {
+		if wordSize == 4 {
+			return uint64(order.Uint32(b))
+		}
+		return order.Uint64(b)
+	}
+
+	detected := values{}
+	for len(data) >= 2*wordSize {
+		key, value := word(data[:wordSize]), word(data[wordSize:2*wordSize])
+		data = data[2*wordSize:]
+		switch key {
+		case atNull:
+			return detected
+		case atPageSz:
+			detected.pageSize = value
+		case atClkTck:
+			detected.clockTicks = value
+		}
+	}
+	return detected
+}
+
+// smapsPageSize returns the KernelPageSize of the first mapping in a smaps file
+func smapsPageSize(path string) (uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("error opening file %s: %w", path, err)
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		value, ok := strings.CutPrefix(sc.Text(), "KernelPageSize:")
+		if !ok {
+			continue
+		}
+		size, err := strconv.ParseUint(strings.TrimSpace(strings.TrimSuffix(value, "kB")), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("error parsing KernelPageSize '%s': %w", value, err)
+		}
+		return size * 1024, nil
+	}
+	if err := sc.Err(); err != nil {
+		return 0, fmt.Errorf("error reading file %s: %w", path, err)
+	}
+	return 0, errors.New("no KernelPageSize found in " + path)
+}
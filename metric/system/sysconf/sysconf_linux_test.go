@@ -0,0 +1,68 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package sysconf
+
+import (
+	"encoding/binary"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-system-metrics/metric/system/resolve"
+)
+
+func TestParseAuxv(t *testing.T) {
+	// AT_HWCAP, AT_PAGESZ, AT_CLKTCK and AT_NULL, followed by padding
+	entries := []uint64{16, 0xbfebfbff, 6, 65536, 17, 100, 0, 0, 6, 4096}
+
+	var le64 []byte
+	for _, v := range entries {
+		le64 = binary.LittleEndian.AppendUint64(le64, v)
+	}
+	assert.Equal(t, values{pageSize: 65536, clockTicks: 100}, parseAuxv(le64, 8, binary.LittleEndian))
+
+	var be32 []byte
+	for _, v := range entries {
+		be32 = binary.BigEndian.AppendUint32(be32, uint32(v))
+	}
+	assert.Equal(t, values{pageSize: 65536, clockTicks: 100}, parseAuxv(be32, 4, binary.BigEndian))
+
+	assert.Equal(t, values{}, parseAuxv(le64[:12], 8, binary.LittleEndian))
+}
+
+func TestSmapsPageSize(t *testing.T) {
+	size, err := smapsPageSize("testdata/smaps")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(65536), size)
+
+	_, err = smapsPageSize("testdata/missing")
+	assert.Error(t, err)
+}
+
+func TestDetect(t *testing.T) {
+	hostfs := resolve.NewTestResolver("/")
+	assert.Equal(t, uint64(os.Getpagesize()), PageSize(hostfs))
+	assert.NotZero(t, ClockTicks(hostfs))
+
+	// an unreadable hostfs falls back to the defaults
+	hostfs = resolve.NewTestResolver(t.TempDir())
+	assert.Equal(t, uint64(os.Getpagesize()), PageSize(hostfs))
+	assert.Equal(t, uint64(defaultClockTicks), ClockTicks(hostfs))
+}